@@ -0,0 +1,61 @@
+package tesla
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// The location of a charging site
+type ChargingSiteLocation struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
+}
+
+// A Supercharger near the vehicle
+type Supercharger struct {
+	Location        ChargingSiteLocation `json:"location"`
+	Name            string               `json:"name"`
+	Type            string               `json:"type"`
+	DistanceMiles   float64              `json:"distance_miles"`
+	AvailableStalls int                  `json:"available_stalls"`
+	TotalStalls     int                  `json:"total_stalls"`
+	SiteClosed      bool                 `json:"site_closed"`
+}
+
+// A destination charger near the vehicle
+type DestinationCharger struct {
+	Location      ChargingSiteLocation `json:"location"`
+	Name          string               `json:"name"`
+	Type          string               `json:"type"`
+	DistanceMiles float64              `json:"distance_miles"`
+}
+
+// Contains the charging sites near the vehicle, ordered by distance
+type ChargingSites struct {
+	CongestionSyncTimeUTCSecs int64                `json:"congestion_sync_time_utc_secs"`
+	DestinationCharging       []DestinationCharger `json:"destination_charging"`
+	Superchargers             []Supercharger       `json:"superchargers"`
+	Timestamp                 int64                `json:"timestamp"`
+}
+
+// Returns the Superchargers and destination chargers near the vehicle
+func (v Vehicle) NearbyChargingSites() (*ChargingSites, error) {
+	return v.NearbyChargingSitesContext(context.Background())
+}
+
+// NearbyChargingSitesContext is like NearbyChargingSites but uses ctx for the request
+func (v Vehicle) NearbyChargingSitesContext(ctx context.Context) (*ChargingSites, error) {
+	sitesResponse := &struct {
+		Response *ChargingSites `json:"response"`
+	}{}
+	body, err := v.client().get(ctx, BaseURL+"/vehicles/"+strconv.FormatInt(v.ID, 10)+"/nearby_charging_sites")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, sitesResponse)
+	if err != nil {
+		return nil, err
+	}
+	return sitesResponse.Response, nil
+}
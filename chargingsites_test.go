@@ -0,0 +1,48 @@
+package tesla
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var NearbyChargingSitesJSON = `{"response":{"congestion_sync_time_utc_secs":1545091987,"destination_charging":[{"location":{"lat":33.790648,"long":-84.383961},"name":"Hyatt Regency Atlanta","type":"destination","distance_miles":1.512}],"superchargers":[{"location":{"lat":33.766075,"long":-84.390487},"name":"Atlanta, GA - Peachtree Street","type":"supercharger","distance_miles":0.512,"available_stalls":6,"total_stalls":8,"site_closed":false},{"location":{"lat":33.848488,"long":-84.368767},"name":"Atlanta, GA - Buckhead","type":"supercharger","distance_miles":5.817,"available_stalls":0,"total_stalls":12,"site_closed":true}],"timestamp":1545092022397}}`
+
+func TestNearbyChargingSitesSpec(t *testing.T) {
+	ts := serveHTTP(t)
+	defer ts.Close()
+	previousAuthURL := AuthURL
+	previousURL := BaseURL
+	AuthURL = ts.URL + "/oauth/token"
+	BaseURL = ts.URL + "/api/1"
+
+	auth := &Auth{
+		GrantType:    "password",
+		ClientID:     "abc123",
+		ClientSecret: "def456",
+		Email:        "elon@tesla.com",
+		Password:     "go",
+	}
+	client, _ := NewClient(auth)
+
+	Convey("Should get the charging sites near the vehicle", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		sites, err := vehicle.NearbyChargingSites()
+		So(err, ShouldBeNil)
+		So(sites.Timestamp, ShouldEqual, 1545092022397)
+		So(len(sites.Superchargers), ShouldEqual, 2)
+		So(sites.Superchargers[0].Name, ShouldEqual, "Atlanta, GA - Peachtree Street")
+		So(sites.Superchargers[0].Location.Lat, ShouldEqual, 33.766075)
+		So(sites.Superchargers[0].AvailableStalls, ShouldEqual, 6)
+		So(sites.Superchargers[0].TotalStalls, ShouldEqual, 8)
+		So(sites.Superchargers[1].SiteClosed, ShouldBeTrue)
+		So(len(sites.DestinationCharging), ShouldEqual, 1)
+		So(sites.DestinationCharging[0].Name, ShouldEqual, "Hyatt Regency Atlanta")
+		So(sites.DestinationCharging[0].DistanceMiles, ShouldEqual, 1.512)
+	})
+
+	AuthURL = previousAuthURL
+	BaseURL = previousURL
+}
@@ -2,10 +2,12 @@ package tesla
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -18,15 +20,32 @@ type Auth struct {
 	Password     string `json:"password"`
 	URL          string
 	StreamingURL string
+
+	// TokenStore, when set, is consulted by NewClient before logging in
+	// and is updated whenever a new token is obtained
+	TokenStore TokenStore `json:"-"`
+
+	// OnSaveError, when set, is called with the error of a token that could
+	// not be saved to TokenStore. The client keeps using the token either way.
+	OnSaveError func(err error) `json:"-"`
 }
 
 // The token and related elements returned after a successful auth
 // by the Tesla API
 type Token struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
-	Expires     int64
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Expires      int64
+}
+
+// The body of a refresh_token grant against the Tesla API
+type refreshRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // Provides the client and associated elements for interacting with the
@@ -35,12 +54,27 @@ type Client struct {
 	Auth  *Auth
 	Token *Token
 	HTTP  *http.Client
+
+	// Retry controls how failed requests are retried, requests are only
+	// attempted once when it is nil
+	Retry *RetryPolicy
+
+	// Guards Token while it is being refreshed
+	mu sync.Mutex
 }
 
 var (
-	AuthURL      = "https://owner-api.teslamotors.com/oauth/token"
-	BaseURL      = "https://owner-api.teslamotors.com/api/1"
+	AuthURL = "https://owner-api.teslamotors.com/oauth/token"
+	BaseURL = "https://owner-api.teslamotors.com/api/1"
+
+	// ActiveClient is the most recently created client. It is only used by
+	// vehicles that were not fetched through a Client.
+	//
+	// Deprecated: use the vehicles returned by Client.Vehicles, which are
+	// bound to the client that fetched them.
 	ActiveClient *Client
+
+	errNoClient = errors.New("no client available for the vehicle, fetch it with Client.Vehicles")
 )
 
 // Generates a new client for the Tesla API
@@ -56,15 +90,53 @@ func NewClient(auth *Auth) (*Client, error) {
 		Auth: auth,
 		HTTP: &http.Client{},
 	}
+	if auth.TokenStore != nil {
+		// A token that can not be loaded, such as a corrupt token file, is
+		// treated as missing and is replaced once logged in
+		token, err := auth.TokenStore.Load()
+		if err == nil && client.useStoredToken(token) {
+			ActiveClient = client
+			return client, nil
+		}
+	}
 	token, err := client.authorize(auth)
 	if err != nil {
 		return nil, err
 	}
 	client.Token = token
+	client.saveToken(token)
 	ActiveClient = client
 	return client, nil
 }
 
+// Adopts a token loaded from the token store when it is still valid or can
+// be refreshed, otherwise the client falls back to logging in
+func (c *Client) useStoredToken(token *Token) bool {
+	if token == nil {
+		return false
+	}
+	c.Token = token
+	if !c.TokenExpired() {
+		return true
+	}
+	if token.RefreshToken != "" && c.RefreshToken() == nil {
+		return true
+	}
+	c.Token = nil
+	return false
+}
+
+// Persists the token to the token store of the client, if any. Failing to
+// save the token does not fail the login or request that obtained it.
+func (c *Client) saveToken(token *Token) {
+	if c.Auth == nil || c.Auth.TokenStore == nil {
+		return
+	}
+	if err := c.Auth.TokenStore.Save(token); err != nil && c.Auth.OnSaveError != nil {
+		c.Auth.OnSaveError(err)
+	}
+}
+
 // NewClientWithToken Generates a new client for the Tesla API using an existing token
 func NewClientWithToken(auth *Auth, token *Token) (*Client, error) {
 	if auth.URL == "" {
@@ -80,24 +152,113 @@ func NewClientWithToken(auth *Auth, token *Token) (*Client, error) {
 		Token: token,
 	}
 	if client.TokenExpired() {
-		return nil, errors.New("supplied token is expired")
+		if token.RefreshToken == "" {
+			return nil, errors.New("supplied token is expired")
+		}
+		if err := client.RefreshToken(); err != nil {
+			return nil, err
+		}
 	}
 	ActiveClient = client
 	return client, nil
 }
 
 // TokenExpired indicates whether an existing token is within an hour of expiration
-func (c Client) TokenExpired() bool {
-	exp := time.Unix(c.Token.Expires, 0)
+func (c *Client) TokenExpired() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Token.expired()
+}
+
+// Indicates whether the token is within an hour of expiration
+func (t *Token) expired() bool {
+	exp := time.Unix(t.Expires, 0)
 	return time.Until(exp) < time.Duration(1*time.Hour)
 }
 
+// RefreshToken exchanges the refresh token of the client for a new access
+// token, which replaces the current Token of the client
+func (c *Client) RefreshToken() error {
+	return c.RefreshTokenContext(context.Background())
+}
+
+// RefreshTokenContext is like RefreshToken but uses ctx for the request
+func (c *Client) RefreshTokenContext(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken(ctx)
+}
+
+// Performs the refresh_token grant, the caller must hold c.mu
+func (c *Client) refreshToken(ctx context.Context) error {
+	if c.Token == nil || c.Token.RefreshToken == "" {
+		return errors.New("no refresh token available")
+	}
+	refresh := &refreshRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: c.Token.RefreshToken,
+	}
+	if c.Auth != nil {
+		refresh.ClientID = c.Auth.ClientID
+		refresh.ClientSecret = c.Auth.ClientSecret
+	}
+	now := time.Now()
+	data, _ := json.Marshal(refresh)
+	req, err := http.NewRequestWithContext(ctx, "POST", AuthURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	body, err := c.doRequest(req, nil)
+	if err != nil {
+		return err
+	}
+	token := &Token{}
+	err = json.Unmarshal(body, token)
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = c.Token.RefreshToken
+	}
+	token.Expires = now.Add(time.Second * time.Duration(token.ExpiresIn)).Unix()
+	c.Token = token
+	c.saveToken(token)
+	return nil
+}
+
+// Returns the token to use for a request, refreshing it first when it is
+// about to expire and can be refreshed
+func (c *Client) currentToken(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Token != nil && c.Token.RefreshToken != "" && c.Token.expired() {
+		if err := c.refreshToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.Token, nil
+}
+
+// Refreshes the token after it was rejected, unless a concurrent request
+// already replaced the stale token
+func (c *Client) refreshStaleToken(ctx context.Context, stale *Token) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Token != stale {
+		return c.Token, nil
+	}
+	if err := c.refreshToken(ctx); err != nil {
+		return nil, err
+	}
+	return c.Token, nil
+}
+
 // Authorizes against the Tesla API with the appropriate credentials
-func (c Client) authorize(auth *Auth) (*Token, error) {
+func (c *Client) authorize(auth *Auth) (*Token, error) {
 	now := time.Now()
 	auth.GrantType = "password"
 	data, _ := json.Marshal(auth)
-	body, err := c.post(AuthURL, data)
+	body, err := c.post(context.Background(), AuthURL, data)
 	if err != nil {
 		return nil, err
 	}
@@ -111,52 +272,123 @@ func (c Client) authorize(auth *Auth) (*Token, error) {
 }
 
 // // Calls an HTTP DELETE
-func (c Client) delete(url string) error {
-	req, _ := http.NewRequest("DELETE", url, nil)
-	_, err := c.processRequest(req)
+func (c *Client) delete(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.processRequest(req)
 	return err
 }
 
 // Calls an HTTP GET
-func (c Client) get(url string) ([]byte, error) {
-	req, _ := http.NewRequest("GET", url, nil)
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
 	return c.processRequest(req)
 }
 
 // Calls an HTTP POST with a JSON body
-func (c Client) post(url string, body []byte) ([]byte, error) {
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
+func (c *Client) post(ctx context.Context, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
 	return c.processRequest(req)
 }
 
 // Calls an HTTP PUT
-func (c Client) put(resource string, body []byte) ([]byte, error) {
-	req, _ := http.NewRequest("PUT", BaseURL+resource, bytes.NewBuffer(body))
+func (c *Client) put(ctx context.Context, resource string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", BaseURL+resource, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
 	return c.processRequest(req)
 }
 
-// Processes a HTTP POST/PUT request
-func (c Client) processRequest(req *http.Request) ([]byte, error) {
-	c.setHeaders(req)
-	res, err := c.HTTP.Do(req)
+// Processes a HTTP POST/PUT request, the request is cancelled along with
+// its context and is retried according to the retry policy of the client
+func (c *Client) processRequest(req *http.Request) ([]byte, error) {
+	if c == nil {
+		return nil, errNoClient
+	}
+	for attempt := 1; ; attempt++ {
+		body, err := c.sendRequest(req)
+		if err == nil || !c.Retry.shouldRetry(req, err, attempt) {
+			return body, err
+		}
+		err = sleepContext(req.Context(), c.Retry.delay(attempt, err))
+		if err != nil {
+			return nil, err
+		}
+		req, err = rewindRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Sends the request once. Expired tokens are refreshed before the request is
+// sent, and the request is repeated with a refreshed token when it is
+// rejected as unauthorized.
+func (c *Client) sendRequest(req *http.Request) ([]byte, error) {
+	token, err := c.currentToken(req.Context())
 	if err != nil {
 		return nil, err
 	}
-	if res.StatusCode != 200 {
-		return nil, errors.New(res.Status)
+	body, err := c.doRequest(req, token)
+	if errors.Is(err, ErrUnauthorized) && token != nil && token.RefreshToken != "" {
+		token, err = c.refreshStaleToken(req.Context(), token)
+		if err != nil {
+			return nil, err
+		}
+		req, err = rewindRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err = c.doRequest(req, token)
+	}
+	return body, err
+}
+
+// Sends a single request with the given token and returns the body of the
+// response, or an *APIError when the status is not 200
+func (c *Client) doRequest(req *http.Request, token *Token) ([]byte, error) {
+	setHeaders(req, token)
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer res.Body.Close()
 	body, err := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		return nil, newAPIError(req, res, body)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return body, nil
 }
 
+// Returns a copy of the request with a fresh body so it can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
 // Sets the required headers for calls to the Tesla API
-func (c Client) setHeaders(req *http.Request) {
-	if c.Token != nil {
-		req.Header.Set("Authorization", "Bearer "+c.Token.AccessToken)
+func setHeaders(req *http.Request, token *Token) {
+	if token != nil {
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -29,7 +29,7 @@ func TestClientSpec(t *testing.T) {
 
 	Convey("Should set the HTTP headers", t, func() {
 		req, _ := http.NewRequest("GET", "http://foo.com", nil)
-		client.setHeaders(req)
+		setHeaders(req, client.Token)
 		So(req.Header.Get("Authorization"), ShouldEqual, "Bearer ghi789")
 		So(req.Header.Get("Accept"), ShouldEqual, "application/json")
 		So(req.Header.Get("Content-Type"), ShouldEqual, "application/json")
@@ -37,6 +37,7 @@ func TestClientSpec(t *testing.T) {
 	Convey("Should login and get an access token", t, func() {
 		So(err, ShouldBeNil)
 		So(client.Token.AccessToken, ShouldEqual, "ghi789")
+		So(client.Token.RefreshToken, ShouldEqual, "jkl012")
 	})
 
 	AuthURL = previousAuthURL
@@ -108,6 +109,102 @@ func TestClientWithTokenSpec(t *testing.T) {
 	BaseURL = previousURL
 }
 
+func TestRefreshTokenSpec(t *testing.T) {
+	refreshes := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		defer req.Body.Close()
+		switch req.URL.String() {
+		case "/oauth/token":
+			refreshes++
+			refresh := &refreshRequest{}
+			json.Unmarshal(body, refresh)
+			if refresh.GrantType != "refresh_token" || refresh.RefreshToken != "jkl012" || refresh.ClientID != "abc123" {
+				w.WriteHeader(400)
+				return
+			}
+			w.Write([]byte(`{"access_token": "fresh", "refresh_token": "mno345", "expires_in": 3888000}`))
+		case "/api/1/vehicles":
+			if req.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(401)
+				return
+			}
+			w.Write([]byte(VehiclesJSON))
+		}
+	}))
+	defer ts.Close()
+	previousAuthURL := AuthURL
+	previousURL := BaseURL
+	AuthURL = ts.URL + "/oauth/token"
+	BaseURL = ts.URL + "/api/1"
+
+	auth := &Auth{
+		ClientID:     "abc123",
+		ClientSecret: "def456",
+	}
+
+	Convey("Should refresh the token on demand", t, func() {
+		client := &Client{Auth: auth, HTTP: &http.Client{}, Token: &Token{AccessToken: "stale", RefreshToken: "jkl012", Expires: 9999999999}}
+		err := client.RefreshToken()
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+		So(client.Token.RefreshToken, ShouldEqual, "mno345")
+		So(client.TokenExpired(), ShouldBeFalse)
+	})
+
+	Convey("Should refresh an expired token before a request", t, func() {
+		refreshes = 0
+		client := &Client{Auth: auth, HTTP: &http.Client{}, Token: &Token{AccessToken: "stale", RefreshToken: "jkl012", Expires: 0}}
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		So(vehicles[0].DisplayName, ShouldEqual, "Macak")
+		So(refreshes, ShouldEqual, 1)
+	})
+
+	Convey("Should refresh the token and retry when unauthorized", t, func() {
+		refreshes = 0
+		client := &Client{Auth: auth, HTTP: &http.Client{}, Token: &Token{AccessToken: "revoked", RefreshToken: "jkl012", Expires: 9999999999}}
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		So(vehicles[0].DisplayName, ShouldEqual, "Macak")
+		So(refreshes, ShouldEqual, 1)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+	})
+
+	Convey("Should not retry when unauthorized without a refresh token", t, func() {
+		refreshes = 0
+		client := &Client{Auth: auth, HTTP: &http.Client{}, Token: &Token{AccessToken: "revoked", Expires: 9999999999}}
+		_, err := client.Vehicles()
+		So(err, ShouldNotBeNil)
+		So(refreshes, ShouldEqual, 0)
+	})
+
+	Convey("Should refresh an expired token when creating a client", t, func() {
+		client, err := NewClientWithToken(auth, &Token{AccessToken: "stale", RefreshToken: "jkl012", Expires: 0})
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+	})
+
+	Convey("Should reject an expired token that cannot be refreshed", t, func() {
+		_, err := NewClientWithToken(auth, &Token{AccessToken: "stale", Expires: 0})
+		So(err, ShouldNotBeNil)
+	})
+
+	AuthURL = previousAuthURL
+	BaseURL = previousURL
+}
+
+// Returns a client holding a valid token, for tests serving the API without
+// the OAuth endpoint
+func newTestClient() *Client {
+	return &Client{Auth: &Auth{}, HTTP: &http.Client{}, Token: &Token{AccessToken: "ghi789", Expires: 99999999999}}
+}
+
+// Returns the vehicle with the ID bound to a new test client
+func newTestVehicle(id int64) *Vehicle {
+	return &Vehicle{ID: id, c: newTestClient()}
+}
+
 func serveHTTP(t *testing.T) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		body, _ := ioutil.ReadAll(req.Body)
@@ -126,11 +223,22 @@ func serveHTTP(t *testing.T) *httptest.Server {
 				So(auth.StreamingURL, ShouldEqual, StreamingURL)
 			})
 			w.WriteHeader(200)
-			w.Write([]byte("{\"access_token\": \"ghi789\"}"))
+			w.Write([]byte("{\"access_token\": \"ghi789\", \"refresh_token\": \"jkl012\", \"expires_in\": 3888000}"))
 		case "/api/1/vehicles":
 			checkHeaders(t, req)
 			w.WriteHeader(200)
 			w.Write([]byte(VehiclesJSON))
+		case "/api/1/vehicles/1234":
+			checkHeaders(t, req)
+			w.WriteHeader(200)
+			w.Write([]byte(VehicleJSON))
+		case "/api/1/vehicles/5678":
+			w.WriteHeader(200)
+			w.Write([]byte(`{"response":null}`))
+		case "/api/1/vehicles/1234/vehicle_data":
+			checkHeaders(t, req)
+			w.WriteHeader(200)
+			w.Write([]byte(vehicleDataJSON()))
 		case "/api/1/vehicles/1234/mobile_enabled":
 			checkHeaders(t, req)
 			w.WriteHeader(200)
@@ -154,6 +262,14 @@ func serveHTTP(t *testing.T) *httptest.Server {
 			checkHeaders(t, req)
 			w.WriteHeader(200)
 			w.Write([]byte(VehicleStateJSON))
+		case "/api/1/vehicles/1234/nearby_charging_sites":
+			checkHeaders(t, req)
+			w.WriteHeader(200)
+			w.Write([]byte(NearbyChargingSitesJSON))
+		case "/api/1/vehicles/1234/data_request/vehicle_config":
+			checkHeaders(t, req)
+			w.WriteHeader(200)
+			w.Write([]byte(VehicleConfigJSON))
 		case "/api/1/vehicles/1234/wake_up":
 			checkHeaders(t, req)
 			w.WriteHeader(200)
@@ -161,8 +277,11 @@ func serveHTTP(t *testing.T) *httptest.Server {
 		case "/api/1/vehicles/1234/command/set_charge_limit":
 			w.WriteHeader(200)
 			Convey("Should receive a set charge limit request", t, func() {
-				So(string(body), ShouldEqual, `{"percent": 50}`)
+				So(string(body), ShouldBeIn, `{"percent":50}`, `{"percent":80}`)
 			})
+			if string(body) == `{"percent":80}` {
+				w.Write([]byte(`{"response":{"reason":"already_set","result":false}}`))
+			}
 		case "/api/1/vehicles/1234/command/charge_standard":
 			checkHeaders(t, req)
 			w.WriteHeader(200)
@@ -174,6 +293,14 @@ func serveHTTP(t *testing.T) *httptest.Server {
 		case "/api/1/vehicles/1234/command/charge_stop",
 			"/api/1/vehicles/1234/command/charge_max_range",
 			"/api/1/vehicles/1234/command/charge_port_door_open",
+			"/api/1/vehicles/1234/command/charge_port_door_close",
+			"/api/1/vehicles/1234/command/media_toggle_playback",
+			"/api/1/vehicles/1234/command/media_next_track",
+			"/api/1/vehicles/1234/command/media_prev_track",
+			"/api/1/vehicles/1234/command/media_next_fav",
+			"/api/1/vehicles/1234/command/media_prev_fav",
+			"/api/1/vehicles/1234/command/media_volume_up",
+			"/api/1/vehicles/1234/command/media_volume_down",
 			"/api/1/vehicles/1234/command/flash_lights",
 			"/api/1/vehicles/1234/command/honk_horn",
 			"/api/1/vehicles/1234/command/auto_conditioning_start",
@@ -181,8 +308,8 @@ func serveHTTP(t *testing.T) *httptest.Server {
 			"/api/1/vehicles/1234/command/door_unlock",
 			"/api/1/vehicles/1234/command/door_lock",
 			"/api/1/vehicles/1234/command/reset_valet_pin",
-			"/api/1/vehicles/1234/command/set_temps?driver_temp=72&passenger_temp=72",
-			"/api/1/vehicles/1234/command/remote_start_drive?password=foo":
+			"/api/1/vehicles/1234/command/cancel_software_update",
+			"/api/1/vehicles/1234/command/set_temps?driver_temp=22&passenger_temp=22":
 			checkHeaders(t, req)
 			w.WriteHeader(200)
 			w.Write([]byte(CommandResponseJSON))
@@ -193,6 +320,120 @@ func serveHTTP(t *testing.T) *httptest.Server {
 				BadStreamEventString + "\n"
 			b := bytes.NewBufferString(events)
 			b.WriteTo(w)
+		case "/api/1/vehicles/1234/command/set_valet_mode":
+			w.WriteHeader(200)
+			Convey("Should receive a valet mode request", t, func() {
+				So(string(body), ShouldBeIn, `{"on":true,"password":"1234"}`, `{"on":false}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/speed_limit_activate",
+			"/api/1/vehicles/1234/command/speed_limit_deactivate",
+			"/api/1/vehicles/1234/command/speed_limit_clear_pin":
+			w.WriteHeader(200)
+			Convey("Should receive a speed limit PIN", t, func() {
+				So(string(body), ShouldEqual, `{"pin":"1234"}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/speed_limit_set_limit":
+			w.WriteHeader(200)
+			Convey("Should receive a speed limit", t, func() {
+				So(string(body), ShouldEqual, `{"limit_mph":70}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/share",
+			"/api/1/vehicles/1234/command/navigation_request":
+			w.WriteHeader(200)
+			Convey("Should receive a navigation request", t, func() {
+				navigationRequest := &NavigationRequest{}
+				err := json.Unmarshal(body, navigationRequest)
+				So(err, ShouldBeNil)
+				So(navigationRequest.Type, ShouldEqual, NavigationShareRaw)
+				So(navigationRequest.Value.Text, ShouldEqual, "3500 Deer Creek Road, Palo Alto, CA")
+				So(navigationRequest.Locale, ShouldBeIn, "en-US", "de-DE")
+				So(navigationRequest.TimestampMs, ShouldNotBeEmpty)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/navigation_gps_request":
+			w.WriteHeader(200)
+			Convey("Should receive a navigation request to coordinates", t, func() {
+				So(string(body), ShouldEqual, `{"lat":37.4,"lon":-122.1,"order":0}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/navigation_sc_request":
+			w.WriteHeader(200)
+			Convey("Should receive a navigation request to a Supercharger", t, func() {
+				So(string(body), ShouldEqual, `{"id":42,"order":0}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/remote_start_drive":
+			checkHeaders(t, req)
+			w.WriteHeader(200)
+			Convey("Should receive the password in the body", t, func() {
+				So(string(body), ShouldBeIn, `{"password":"foo"}`, `{}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/schedule_software_update":
+			w.WriteHeader(200)
+			Convey("Should receive a software update offset", t, func() {
+				So(string(body), ShouldEqual, `{"offset_sec":7200}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/remote_seat_heater_request":
+			w.WriteHeader(200)
+			Convey("Should receive a seat heater request", t, func() {
+				So(string(body), ShouldEqual, `{"heater":5,"level":2}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/remote_steering_wheel_heater_request",
+			"/api/1/vehicles/1234/command/set_preconditioning_max",
+			"/api/1/vehicles/1234/command/set_sentry_mode":
+			w.WriteHeader(200)
+			Convey("Should receive a request to switch on", t, func() {
+				So(string(body), ShouldEqual, `{"on":true}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/set_bioweapon_mode":
+			w.WriteHeader(200)
+			Convey("Should receive a bioweapon defense mode request", t, func() {
+				So(string(body), ShouldEqual, `{"on":false,"manual_override":true}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/set_climate_keeper_mode":
+			w.WriteHeader(200)
+			Convey("Should receive a climate keeper mode request", t, func() {
+				So(string(body), ShouldEqual, `{"climate_keeper_mode":2}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/set_cabin_overheat_protection":
+			w.WriteHeader(200)
+			Convey("Should receive a cabin overheat protection request", t, func() {
+				So(string(body), ShouldEqual, `{"on":true,"fan_only":true}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/set_charging_amps":
+			w.WriteHeader(200)
+			Convey("Should receive a charging amps request", t, func() {
+				So(string(body), ShouldEqual, `{"charging_amps":16}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/set_scheduled_charging":
+			w.WriteHeader(200)
+			Convey("Should receive a scheduled charging request", t, func() {
+				So(string(body), ShouldEqual, `{"enable":true,"time":120}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/set_scheduled_departure":
+			w.WriteHeader(200)
+			Convey("Should receive a scheduled departure request", t, func() {
+				So(string(body), ShouldEqual, `{"enable":true,"departure_time":450,"preconditioning_enabled":true,"preconditioning_weekdays_only":false,"off_peak_charging_enabled":true,"off_peak_charging_weekdays_only":true,"end_off_peak_time":360}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/adjust_volume":
+			w.WriteHeader(200)
+			Convey("Should receive a volume request", t, func() {
+				So(string(body), ShouldEqual, `{"volume":0}`)
+			})
+			w.Write([]byte(CommandResponseJSON))
 		case "/api/1/vehicles/1234/command/autopark_request":
 			w.WriteHeader(200)
 			Convey("Auto park request should have appropriate body", t, func() {
@@ -218,21 +459,31 @@ func serveHTTP(t *testing.T) *httptest.Server {
 			Convey("Should set the Pano roof appropriately", t, func() {
 				passed := false
 				strBody := string(body)
-				if strBody == `{"state": "vent", "percent":0}` {
+				if strBody == `{"state":"vent","percent":0}` {
 					passed = true
 				}
-				if strBody == `{"state": "open", "percent":0}` {
+				if strBody == `{"state":"open","percent":0}` {
 					passed = true
 				}
-				if strBody == `{"state": "move", "percent":50}` {
+				if strBody == `{"state":"move","percent":50}` {
 					passed = true
 				}
-				if strBody == `{"state": "close", "percent":0}` {
+				if strBody == `{"state":"close","percent":0}` {
 					passed = true
 				}
 				So(passed, ShouldBeTrue)
 
 			})
+		case "/api/1/vehicles/1234/command/window_control":
+			w.WriteHeader(200)
+			Convey("Window control request should have appropriate body", t, func() {
+				windowRequest := &WindowControlRequest{}
+				err := json.Unmarshal(body, windowRequest)
+				So(err, ShouldBeNil)
+				So(windowRequest.Command, ShouldBeIn, WindowVent, WindowClose)
+				So(windowRequest.Lat, ShouldEqual, 35.1)
+				So(windowRequest.Lon, ShouldEqual, 20.2)
+			})
 		}
 	}))
 }
@@ -1,9 +1,11 @@
 package tesla
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strconv"
+	"time"
 )
 
 // Response from the Tesla API after POSTing a command
@@ -23,29 +25,241 @@ type AutoParkRequest struct {
 	Action    string  `json:"action,omitempty"`
 }
 
+// How often WakeUpAndWait polls the vehicle, backing off up to the maximum
+var (
+	wakeupPollInterval    = time.Second
+	wakeupMaxPollInterval = 10 * time.Second
+)
+
+// The seats that may be heated with SetSeatHeater
+type Seat int
+
+const (
+	SeatDriver     Seat = 0
+	SeatPassenger  Seat = 1
+	SeatRearLeft   Seat = 2
+	SeatRearCenter Seat = 4
+	SeatRearRight  Seat = 5
+)
+
+// The modes that keep the climate on after leaving the vehicle
+type ClimateKeeperMode int
+
+const (
+	ClimateKeeperOff  ClimateKeeperMode = 0
+	ClimateKeeperKeep ClimateKeeperMode = 1
+	ClimateKeeperDog  ClimateKeeperMode = 2
+	ClimateKeeperCamp ClimateKeeperMode = 3
+)
+
+// Required elements to POST a command that turns a feature on or off
+type SwitchRequest struct {
+	On bool `json:"on"`
+}
+
+// Required elements to POST a seat heater request
+type SeatHeaterRequest struct {
+	Heater Seat `json:"heater"`
+	Level  int  `json:"level"`
+}
+
+// Required elements to POST a bioweapon defense mode request
+type BioweaponModeRequest struct {
+	On             bool `json:"on"`
+	ManualOverride bool `json:"manual_override"`
+}
+
+// Required elements to POST a climate keeper mode request
+type ClimateKeeperRequest struct {
+	Mode ClimateKeeperMode `json:"climate_keeper_mode"`
+}
+
+// Required elements to POST a cabin overheat protection request
+type CabinOverheatProtectionRequest struct {
+	On      bool `json:"on"`
+	FanOnly bool `json:"fan_only"`
+}
+
+// Required elements to POST a charge limit request
+type ChargeLimitRequest struct {
+	Percent int `json:"percent"`
+}
+
+// Required elements to POST a charging amps request
+type ChargingAmpsRequest struct {
+	Amps int `json:"charging_amps"`
+}
+
+// Required elements to POST a scheduled charging request, the time is in
+// minutes after midnight
+type ScheduledChargingRequest struct {
+	Enable bool `json:"enable"`
+	Time   int  `json:"time"`
+}
+
+// The departure the vehicle prepares for, times are in minutes after midnight
+type ScheduledDeparture struct {
+	Enable                      bool `json:"enable"`
+	DepartureTime               int  `json:"departure_time"`
+	PreconditioningEnabled      bool `json:"preconditioning_enabled"`
+	PreconditioningWeekdaysOnly bool `json:"preconditioning_weekdays_only"`
+	OffPeakChargingEnabled      bool `json:"off_peak_charging_enabled"`
+	OffPeakChargingWeekdaysOnly bool `json:"off_peak_charging_weekdays_only"`
+	EndOffPeakTime              int  `json:"end_off_peak_time"`
+}
+
+// The loudest volume that may be set with AdjustVolume
+const maxVolume = 11
+
+// Required elements to POST a volume request
+type VolumeRequest struct {
+	Volume float64 `json:"volume"`
+}
+
+// Required elements to POST a valet mode request
+type ValetModeRequest struct {
+	On       bool   `json:"on"`
+	Password string `json:"password,omitempty"`
+}
+
+// Required elements to POST a speed limit PIN request
+type SpeedLimitPINRequest struct {
+	PIN string `json:"pin"`
+}
+
+// Required elements to POST a speed limit request
+type SpeedLimitRequest struct {
+	LimitMph int `json:"limit_mph"`
+}
+
+// Required elements to POST a remote start request, the password is left
+// out for accounts that do not require it
+type RemoteStartRequest struct {
+	Password string `json:"password,omitempty"`
+}
+
+// Required elements to POST a software update schedule request
+type SoftwareUpdateRequest struct {
+	OffsetSec int `json:"offset_sec"`
+}
+
+// The type of content shared with the vehicle in a NavigationRequest
+const NavigationShareRaw = "share_ext_content_raw"
+
+// Required elements to POST a navigation or share request, which sends a
+// destination to the navigation system of the vehicle
+type NavigationRequest struct {
+	Type        string          `json:"type"`
+	Value       NavigationValue `json:"value"`
+	Locale      string          `json:"locale"`
+	TimestampMs string          `json:"timestamp_ms"`
+}
+
+// The content shared with the vehicle, usually an address
+type NavigationValue struct {
+	Text string `json:"android.intent.extra.TEXT"`
+}
+
+// Required elements to POST a navigation request to coordinates
+type NavigationGPSRequest struct {
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	Order int     `json:"order"`
+}
+
+// Required elements to POST a navigation request to a Supercharger
+type NavigationSuperchargerRequest struct {
+	ID    int64 `json:"id"`
+	Order int   `json:"order"`
+}
+
+// The trunks of the vehicle
+type Trunk string
+
+const (
+	TrunkFront Trunk = "front"
+	TrunkRear  Trunk = "rear"
+)
+
+// The states the panoramic roof may be moved to
+type SunRoofState string
+
+const (
+	SunRoofOpen    SunRoofState = "open"
+	SunRoofClose   SunRoofState = "close"
+	SunRoofComfort SunRoofState = "comfort"
+	SunRoofVent    SunRoofState = "vent"
+	SunRoofMove    SunRoofState = "move"
+)
+
+// The commands that may be sent to the windows
+type WindowCommand string
+
+const (
+	WindowVent  WindowCommand = "vent"
+	WindowClose WindowCommand = "close"
+)
+
+// Required elements to POST a trunk request
+type TrunkRequest struct {
+	WhichTrunk Trunk `json:"which_trunk"`
+}
+
+// Required elements to POST a panoramic roof request
+type SunRoofRequest struct {
+	State   SunRoofState `json:"state"`
+	Percent int          `json:"percent"`
+}
+
+// Required elements to POST a window control request
+type WindowControlRequest struct {
+	Command WindowCommand `json:"command"`
+	Lat     float64       `json:"lat"`
+	Lon     float64       `json:"lon"`
+}
+
+// Required elements to POST a sentry mode request
 type SentryData struct {
-	Mode string `json:"on"`
+	On bool `json:"on"`
 }
 
 // Causes the vehicle to abort the Autopark request
 func (v Vehicle) AutoparkAbort() error {
-	return v.autoPark("abort")
+	return v.AutoparkAbortContext(context.Background())
+}
+
+// AutoparkAbortContext is like AutoparkAbort but uses ctx for the request
+func (v Vehicle) AutoparkAbortContext(ctx context.Context) error {
+	return v.autoPark(ctx, "abort")
 }
 
 // Causes the vehicle to pull forward
 func (v Vehicle) AutoparkForward() error {
-	return v.autoPark("start_forward")
+	return v.AutoparkForwardContext(context.Background())
+}
+
+// AutoparkForwardContext is like AutoparkForward but uses ctx for the request
+func (v Vehicle) AutoparkForwardContext(ctx context.Context) error {
+	return v.autoPark(ctx, "start_forward")
 }
 
 // Causes the vehicle to go in reverse
 func (v Vehicle) AutoparkReverse() error {
-	return v.autoPark("start_reverse")
+	return v.AutoparkReverseContext(context.Background())
+}
+
+// AutoparkReverseContext is like AutoparkReverse but uses ctx for the request
+func (v Vehicle) AutoparkReverseContext(ctx context.Context) error {
+	return v.autoPark(ctx, "start_reverse")
 }
 
 // Performs the actual auto park/summon request for the vehicle
-func (v Vehicle) autoPark(action string) error {
+func (v Vehicle) autoPark(ctx context.Context, action string) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/autopark_request"
-	driveState, _ := v.DriveState()
+	driveState, err := v.DriveStateContext(ctx)
+	if err != nil {
+		return err
+	}
 	autoParkRequest := &AutoParkRequest{
 		VehicleID: v.VehicleID,
 		Lat:       driveState.Latitude,
@@ -54,57 +268,189 @@ func (v Vehicle) autoPark(action string) error {
 	}
 	body, _ := json.Marshal(autoParkRequest)
 
-	_, err := sendCommand(apiUrl, body)
+	_, err = v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
 // Enables Sentry Mode
 func (v *Vehicle) EnableSentry() error {
-	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_sentry_mode"
-	sentryRequest := &SentryData{
-		Mode: "true",
-	}
+	return v.EnableSentryContext(context.Background())
+}
+
+// EnableSentryContext is like EnableSentry but uses ctx for the request
+func (v *Vehicle) EnableSentryContext(ctx context.Context) error {
+	return v.SetSentryModeContext(ctx, true)
+}
+
+// Disables Sentry Mode
+func (v Vehicle) DisableSentry() error {
+	return v.DisableSentryContext(context.Background())
+}
 
-	body, _ := json.Marshal(sentryRequest)
-	_, err := sendCommand(apiUrl, body)
+// DisableSentryContext is like DisableSentry but uses ctx for the request
+func (v Vehicle) DisableSentryContext(ctx context.Context) error {
+	return v.SetSentryModeContext(ctx, false)
+}
+
+// Turns Sentry Mode on or off
+func (v Vehicle) SetSentryMode(on bool) error {
+	return v.SetSentryModeContext(context.Background(), on)
+}
+
+// SetSentryModeContext is like SetSentryMode but uses ctx for the request
+func (v Vehicle) SetSentryModeContext(ctx context.Context, on bool) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_sentry_mode"
+	body, _ := json.Marshal(&SentryData{On: on})
+	_, err := v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
-// TBD based on Github issue #7
-// Toggles defrost on and off, locations values are 'front' or 'rear'
-// func (v Vehicle) Defrost(location string, state bool) error {
-// 	command := location + "_defrost_"
-// 	if state {
-// 		command += "on"
-// 	} else {
-// 		command += "off"
-// 	}
-// 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/" + command
-// 	fmt.Println(apiUrl)
-// 	_, err := sendCommand(apiUrl, nil)
-// 	return err
-// }
+// Turns Sentry Mode on or off after reading the vehicle state. Nothing is
+// sent if Sentry Mode is already in the requested state, and
+// ErrSentryModeUnavailable is returned if the vehicle does not support it.
+func (v Vehicle) EnsureSentryMode(on bool) error {
+	return v.EnsureSentryModeContext(context.Background(), on)
+}
+
+// EnsureSentryModeContext is like EnsureSentryMode but uses ctx for the requests
+func (v Vehicle) EnsureSentryModeContext(ctx context.Context, on bool) error {
+	vehicleState, err := v.VehicleStateContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !vehicleState.SentryModeAvailable {
+		return ErrSentryModeUnavailable
+	}
+	if vehicleState.SentryMode == on {
+		return nil
+	}
+	return v.SetSentryModeContext(ctx, on)
+}
 
 // Opens and closes the configured Homelink garage door of the vehicle
 // keep in mind this is a toggle and the garage door state is unknown
 // a major limitation of Homelink
 func (v Vehicle) TriggerHomelink() error {
+	return v.TriggerHomelinkContext(context.Background())
+}
+
+// TriggerHomelinkContext is like TriggerHomelink but uses ctx for the request
+func (v Vehicle) TriggerHomelinkContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/trigger_homelink"
-	driveState, _ := v.DriveState()
+	driveState, err := v.DriveStateContext(ctx)
+	if err != nil {
+		return err
+	}
 	autoParkRequest := &AutoParkRequest{
 		Lat: driveState.Latitude,
 		Lon: driveState.Longitude,
 	}
 	body, _ := json.Marshal(autoParkRequest)
 
-	_, err := sendCommand(apiUrl, body)
+	_, err = v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Shares an address with the vehicle, which sets it as the navigation
+// destination. The locale defaults to en-US when empty.
+func (v Vehicle) ShareAddress(address string, locale string) error {
+	return v.ShareAddressContext(context.Background(), address, locale)
+}
+
+// ShareAddressContext is like ShareAddress but uses ctx for the request
+func (v Vehicle) ShareAddressContext(ctx context.Context, address string, locale string) error {
+	if address == "" {
+		return &ValidationError{Field: "address", Value: address, Reason: "must not be empty"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/share"
+	body, _ := json.Marshal(newNavigationRequest(address, locale))
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Sends the navigation request to the vehicle as is
+func (v Vehicle) Navigate(request *NavigationRequest) error {
+	return v.NavigateContext(context.Background(), request)
+}
+
+// NavigateContext is like Navigate but uses ctx for the request
+func (v Vehicle) NavigateContext(ctx context.Context, request *NavigationRequest) error {
+	if request == nil || request.Value.Text == "" {
+		return &ValidationError{Field: "value", Value: request, Reason: "must not be empty"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/navigation_request"
+	body, _ := json.Marshal(request)
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Sets the navigation destination of the vehicle to the coordinates
+func (v Vehicle) NavigateToCoordinates(lat float64, lon float64) error {
+	return v.NavigateToCoordinatesContext(context.Background(), lat, lon)
+}
+
+// NavigateToCoordinatesContext is like NavigateToCoordinates but uses ctx for the request
+func (v Vehicle) NavigateToCoordinatesContext(ctx context.Context, lat float64, lon float64) error {
+	if err := validateCoordinates(lat, lon); err != nil {
+		return err
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/navigation_gps_request"
+	body, _ := json.Marshal(&NavigationGPSRequest{Lat: lat, Lon: lon})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Sets the navigation destination of the vehicle to the Supercharger with
+// the given ID
+func (v Vehicle) NavigateToSupercharger(id int64) error {
+	return v.NavigateToSuperchargerContext(context.Background(), id)
+}
+
+// NavigateToSuperchargerContext is like NavigateToSupercharger but uses ctx for the request
+func (v Vehicle) NavigateToSuperchargerContext(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return &ValidationError{Field: "id", Value: id, Reason: "must be positive"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/navigation_sc_request"
+	body, _ := json.Marshal(&NavigationSuperchargerRequest{ID: id})
+	_, err := v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
+// Checks that the coordinates lie within the range of a latitude and a
+// longitude
+func validateCoordinates(lat float64, lon float64) error {
+	if lat < -90 || lat > 90 {
+		return &ValidationError{Field: "lat", Value: lat, Reason: "must be between -90 and 90"}
+	}
+	if lon < -180 || lon > 180 {
+		return &ValidationError{Field: "lon", Value: lon, Reason: "must be between -180 and 180"}
+	}
+	return nil
+}
+
+// Builds a request sharing the text with the vehicle
+func newNavigationRequest(text string, locale string) *NavigationRequest {
+	if locale == "" {
+		locale = "en-US"
+	}
+	return &NavigationRequest{
+		Type:        NavigationShareRaw,
+		Value:       NavigationValue{Text: text},
+		Locale:      locale,
+		TimestampMs: strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+	}
+}
+
 // Wakes up the vehicle when it is powered off
 func (v Vehicle) Wakeup() (*Vehicle, error) {
+	return v.WakeupContext(context.Background())
+}
+
+// WakeupContext is like Wakeup but uses ctx for the request
+func (v Vehicle) WakeupContext(ctx context.Context) (*Vehicle, error) {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/wake_up"
-	body, err := sendCommand(apiUrl, nil)
+	body, err := v.sendCommand(ctx, apiUrl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -113,141 +459,818 @@ func (v Vehicle) Wakeup() (*Vehicle, error) {
 	if err != nil {
 		return nil, err
 	}
+	if vehicleResponse.Response != nil {
+		vehicleResponse.Response.c = v.client()
+	}
 	return vehicleResponse.Response, nil
 }
 
+// WakeUpAndWait wakes up the vehicle and polls it until it is online,
+// returning the refreshed vehicle, or ErrWakeupTimeout once timeout elapses
+func (v Vehicle) WakeUpAndWait(ctx context.Context, timeout time.Duration) (*Vehicle, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	interval := wakeupPollInterval
+	for {
+		vehicle, err := v.WakeupContext(waitCtx)
+		if err == nil && !vehicleOnline(vehicle) {
+			vehicle, err = v.client().VehicleContext(waitCtx, v.ID)
+		}
+		if err == nil && vehicleOnline(vehicle) {
+			return vehicle, nil
+		}
+		if err != nil && waitCtx.Err() == nil && !errors.Is(err, ErrVehicleUnavailable) {
+			return nil, err
+		}
+		if sleepContext(waitCtx, interval) != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, ErrWakeupTimeout
+		}
+		interval *= 2
+		if interval > wakeupMaxPollInterval {
+			interval = wakeupMaxPollInterval
+		}
+	}
+}
+
+// Indicates whether the vehicle is online, a vehicle missing from the
+// response is not online yet
+func vehicleOnline(vehicle *Vehicle) bool {
+	return vehicle != nil && vehicle.State == "online"
+}
+
 // Opens the charge port so you may insert your charging cable
 func (v Vehicle) OpenChargePort() error {
+	return v.OpenChargePortContext(context.Background())
+}
+
+// OpenChargePortContext is like OpenChargePort but uses ctx for the request
+func (v Vehicle) OpenChargePortContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/charge_port_door_open"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
+// Closes the charge port, if the vehicle has a motorized charge port
+func (v Vehicle) CloseChargePort() error {
+	return v.CloseChargePortContext(context.Background())
+}
+
+// CloseChargePortContext is like CloseChargePort but uses ctx for the request
+func (v Vehicle) CloseChargePortContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/charge_port_door_close"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// SetChargingAmps sets the current the vehicle charges with, which may not
+// exceed the ChargeCurrentRequestMax of the charge state
+func (v Vehicle) SetChargingAmps(amps int) error {
+	return v.SetChargingAmpsContext(context.Background(), amps)
+}
+
+// SetChargingAmpsContext is like SetChargingAmps but uses ctx for the requests
+func (v Vehicle) SetChargingAmpsContext(ctx context.Context, amps int) error {
+	if amps < 1 {
+		return &ValidationError{Field: "charging_amps", Value: amps, Reason: "must be at least 1"}
+	}
+	chargeState, err := v.ChargeStateContext(ctx)
+	if err != nil {
+		return err
+	}
+	if maxAmps := chargeState.ChargeCurrentRequestMax; maxAmps > 0 && amps > maxAmps {
+		return &ValidationError{Field: "charging_amps", Value: amps, Reason: "must be between 1 and " + strconv.Itoa(maxAmps)}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_charging_amps"
+	body, _ := json.Marshal(&ChargingAmpsRequest{Amps: amps})
+	_, err = v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetScheduledCharging enables or disables charging at a scheduled time,
+// given in minutes after midnight local time
+func (v Vehicle) SetScheduledCharging(enable bool, minutesAfterMidnight int) error {
+	return v.SetScheduledChargingContext(context.Background(), enable, minutesAfterMidnight)
+}
+
+// SetScheduledChargingContext is like SetScheduledCharging but uses ctx for the request
+func (v Vehicle) SetScheduledChargingContext(ctx context.Context, enable bool, minutesAfterMidnight int) error {
+	if err := validateMinutesAfterMidnight("time", minutesAfterMidnight); err != nil {
+		return err
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_scheduled_charging"
+	body, _ := json.Marshal(&ScheduledChargingRequest{Enable: enable, Time: minutesAfterMidnight})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetScheduledDeparture sets the departure time the vehicle prepares for,
+// by preconditioning the cabin and charging during off-peak hours
+func (v Vehicle) SetScheduledDeparture(departure *ScheduledDeparture) error {
+	return v.SetScheduledDepartureContext(context.Background(), departure)
+}
+
+// SetScheduledDepartureContext is like SetScheduledDeparture but uses ctx for the request
+func (v Vehicle) SetScheduledDepartureContext(ctx context.Context, departure *ScheduledDeparture) error {
+	if departure == nil {
+		return &ValidationError{Field: "departure", Value: departure, Reason: "must not be nil"}
+	}
+	if err := validateMinutesAfterMidnight("departure_time", departure.DepartureTime); err != nil {
+		return err
+	}
+	if err := validateMinutesAfterMidnight("end_off_peak_time", departure.EndOffPeakTime); err != nil {
+		return err
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_scheduled_departure"
+	body, _ := json.Marshal(departure)
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Checks a time of day given in minutes after midnight
+func validateMinutesAfterMidnight(field string, minutes int) error {
+	if minutes < 0 || minutes >= 24*60 {
+		return &ValidationError{Field: field, Value: minutes, Reason: "must be between 0 and 1439 minutes after midnight"}
+	}
+	return nil
+}
+
 // Resets the PIN set for valet mode, if set
 func (v Vehicle) ResetValetPIN() error {
+	return v.ResetValetPINContext(context.Background())
+}
+
+// ResetValetPINContext is like ResetValetPIN but uses ctx for the request
+func (v Vehicle) ResetValetPINContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/reset_valet_pin"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Turns valet mode on or off. The PIN is optional when turning valet mode
+// on, in which case the PIN set on the touchscreen is used.
+func (v Vehicle) SetValetMode(on bool, pin string) error {
+	return v.SetValetModeContext(context.Background(), on, pin)
+}
+
+// SetValetModeContext is like SetValetMode but uses ctx for the request
+func (v Vehicle) SetValetModeContext(ctx context.Context, on bool, pin string) error {
+	if pin != "" {
+		if err := validatePIN(pin); err != nil {
+			return err
+		}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_valet_mode"
+	body, _ := json.Marshal(&ValetModeRequest{On: on, Password: pin})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Activates speed limit mode, protected by the given four digit PIN
+func (v Vehicle) SpeedLimitActivate(pin string) error {
+	return v.SpeedLimitActivateContext(context.Background(), pin)
+}
+
+// SpeedLimitActivateContext is like SpeedLimitActivate but uses ctx for the request
+func (v Vehicle) SpeedLimitActivateContext(ctx context.Context, pin string) error {
+	return v.speedLimitPIN(ctx, "speed_limit_activate", pin)
+}
+
+// Deactivates speed limit mode with the PIN it was activated with
+func (v Vehicle) SpeedLimitDeactivate(pin string) error {
+	return v.SpeedLimitDeactivateContext(context.Background(), pin)
+}
+
+// SpeedLimitDeactivateContext is like SpeedLimitDeactivate but uses ctx for the request
+func (v Vehicle) SpeedLimitDeactivateContext(ctx context.Context, pin string) error {
+	return v.speedLimitPIN(ctx, "speed_limit_deactivate", pin)
+}
+
+// Clears the speed limit mode PIN, given the current PIN
+func (v Vehicle) SpeedLimitClearPIN(pin string) error {
+	return v.SpeedLimitClearPINContext(context.Background(), pin)
+}
+
+// SpeedLimitClearPINContext is like SpeedLimitClearPIN but uses ctx for the request
+func (v Vehicle) SpeedLimitClearPINContext(ctx context.Context, pin string) error {
+	return v.speedLimitPIN(ctx, "speed_limit_clear_pin", pin)
+}
+
+// Sends a speed limit command that only carries a PIN
+func (v Vehicle) speedLimitPIN(ctx context.Context, command string, pin string) error {
+	if err := validatePIN(pin); err != nil {
+		return err
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/" + command
+	body, _ := json.Marshal(&SpeedLimitPINRequest{PIN: pin})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Sets the maximum speed in mph while speed limit mode is active. The limit
+// is validated against the range reported in the vehicle state.
+func (v Vehicle) SpeedLimitSetLimit(mph int) error {
+	return v.SpeedLimitSetLimitContext(context.Background(), mph)
+}
+
+// SpeedLimitSetLimitContext is like SpeedLimitSetLimit but uses ctx for the requests
+func (v Vehicle) SpeedLimitSetLimitContext(ctx context.Context, mph int) error {
+	vehicleState, err := v.VehicleStateContext(ctx)
+	if err != nil {
+		return err
+	}
+	if limits := vehicleState.SpeedLimitMode; limits != nil && limits.MaxLimitMph > 0 {
+		if float64(mph) < limits.MinLimitMph || float64(mph) > limits.MaxLimitMph {
+			return &ValidationError{Field: "limit_mph", Value: mph, Reason: "must be between " + strconv.FormatFloat(limits.MinLimitMph, 'f', -1, 64) + " and " + strconv.FormatFloat(limits.MaxLimitMph, 'f', -1, 64)}
+		}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/speed_limit_set_limit"
+	body, _ := json.Marshal(&SpeedLimitRequest{LimitMph: mph})
+	_, err = v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Checks that the PIN is made up of four digits
+func validatePIN(pin string) error {
+	if len(pin) != 4 {
+		return &ValidationError{Field: "pin", Value: pin, Reason: "must be four digits"}
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return &ValidationError{Field: "pin", Value: pin, Reason: "must be four digits"}
+		}
+	}
+	return nil
+}
+
+// Schedules the installation of an available software update to start after
+// the given offset, an offset of zero starts the installation right away
+func (v Vehicle) ScheduleSoftwareUpdate(offset time.Duration) error {
+	return v.ScheduleSoftwareUpdateContext(context.Background(), offset)
+}
+
+// ScheduleSoftwareUpdateContext is like ScheduleSoftwareUpdate but uses ctx for the request
+func (v Vehicle) ScheduleSoftwareUpdateContext(ctx context.Context, offset time.Duration) error {
+	if offset < 0 {
+		return &ValidationError{Field: "offset_sec", Value: offset, Reason: "must not be negative"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/schedule_software_update"
+	body, _ := json.Marshal(&SoftwareUpdateRequest{OffsetSec: int(offset / time.Second)})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Cancels a scheduled software update, the update remains available
+func (v Vehicle) CancelSoftwareUpdate() error {
+	return v.CancelSoftwareUpdateContext(context.Background())
+}
+
+// CancelSoftwareUpdateContext is like CancelSoftwareUpdate but uses ctx for the request
+func (v Vehicle) CancelSoftwareUpdateContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/cancel_software_update"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Sets the charge limit to the standard setting
 func (v Vehicle) SetChargeLimitStandard() error {
+	return v.SetChargeLimitStandardContext(context.Background())
+}
+
+// SetChargeLimitStandardContext is like SetChargeLimitStandard but uses ctx for the request
+func (v Vehicle) SetChargeLimitStandardContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/charge_standard"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Sets the charge limit to the max limit
 func (v Vehicle) SetChargeLimitMax() error {
+	return v.SetChargeLimitMaxContext(context.Background())
+}
+
+// SetChargeLimitMaxContext is like SetChargeLimitMax but uses ctx for the request
+func (v Vehicle) SetChargeLimitMaxContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/charge_max_range"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Set the charge limit to a custom percentage
 func (v Vehicle) SetChargeLimit(percent int) error {
+	return v.SetChargeLimitContext(context.Background(), percent)
+}
+
+// SetChargeLimitContext is like SetChargeLimit but uses ctx for the request
+func (v Vehicle) SetChargeLimitContext(ctx context.Context, percent int) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_charge_limit"
-	theJson := `{"percent": ` + strconv.Itoa(percent) + `}`
-	_, err := ActiveClient.post(apiUrl, []byte(theJson))
+	body, _ := json.Marshal(&ChargeLimitRequest{Percent: percent})
+	_, err := v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
 // StartCharging starts the charging of the vehicle after you have inserted the
 // charging cable
 func (v Vehicle) StartCharging() error {
+	return v.StartChargingContext(context.Background())
+}
+
+// StartChargingContext is like StartCharging but uses ctx for the request
+func (v Vehicle) StartChargingContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/charge_start"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Stop the charging of the vehicle
 func (v Vehicle) StopCharging() error {
+	return v.StopChargingContext(context.Background())
+}
+
+// StopChargingContext is like StopCharging but uses ctx for the request
+func (v Vehicle) StopChargingContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/charge_stop"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Flashes the lights of the vehicle
 func (v Vehicle) FlashLights() error {
+	return v.FlashLightsContext(context.Background())
+}
+
+// FlashLightsContext is like FlashLights but uses ctx for the request
+func (v Vehicle) FlashLightsContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/flash_lights"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Honks the horn of the vehicle
 func (v *Vehicle) HonkHorn() error {
+	return v.HonkHornContext(context.Background())
+}
+
+// HonkHornContext is like HonkHorn but uses ctx for the request
+func (v *Vehicle) HonkHornContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/honk_horn"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Unlock the car's doors
 func (v Vehicle) UnlockDoors() error {
+	return v.UnlockDoorsContext(context.Background())
+}
+
+// UnlockDoorsContext is like UnlockDoors but uses ctx for the request
+func (v Vehicle) UnlockDoorsContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/door_unlock"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Locks the doors of the vehicle
 func (v Vehicle) LockDoors() error {
+	return v.LockDoorsContext(context.Background())
+}
+
+// LockDoorsContext is like LockDoors but uses ctx for the request
+func (v Vehicle) LockDoorsContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/door_lock"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
 // Sets the temprature of the vehicle, where you may set the driver
 // zone and the passenger zone to seperate temperatures
 func (v Vehicle) SetTemprature(driver float64, passenger float64) error {
+	return v.SetTempratureContext(context.Background(), driver, passenger)
+}
+
+// SetTempratureContext is like SetTemprature but uses ctx for the request
+func (v Vehicle) SetTempratureContext(ctx context.Context, driver float64, passenger float64) error {
+	climateState, err := v.ClimateStateContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err = validateTemperature("driver_temp", driver, climateState); err != nil {
+		return err
+	}
+	if err = validateTemperature("passenger_temp", passenger, climateState); err != nil {
+		return err
+	}
 	driveTemp := strconv.FormatFloat(driver, 'f', -1, 32)
 	passengerTemp := strconv.FormatFloat(passenger, 'f', -1, 32)
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_temps?driver_temp=" + driveTemp + "&passenger_temp=" + passengerTemp
-	_, err := ActiveClient.post(apiUrl, nil)
+	_, err = v.sendCommand(ctx, apiUrl, nil)
 	return err
 }
 
+// Checks a temperature in celsius against the range the vehicle supports
+func validateTemperature(field string, temp float64, climateState *ClimateState) error {
+	if climateState.MinAvailTemp == 0 && climateState.MaxAvailTemp == 0 {
+		return nil
+	}
+	if temp < climateState.MinAvailTemp || temp > climateState.MaxAvailTemp {
+		return &ValidationError{
+			Field:  field,
+			Value:  temp,
+			Reason: "must be between " + strconv.FormatFloat(climateState.MinAvailTemp, 'f', -1, 64) + " and " + strconv.FormatFloat(climateState.MaxAvailTemp, 'f', -1, 64),
+		}
+	}
+	return nil
+}
+
 // StartAirConditioning starts the air conditioning in the car
 func (v Vehicle) StartAirConditioning() error {
+	return v.StartAirConditioningContext(context.Background())
+}
+
+// StartAirConditioningContext is like StartAirConditioning but uses ctx for the request
+func (v Vehicle) StartAirConditioningContext(ctx context.Context) error {
 	url := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/auto_conditioning_start"
-	_, err := sendCommand(url, nil)
+	_, err := v.sendCommand(ctx, url, nil)
 	return err
 }
 
 // Stops the air conditioning in the car
 func (v Vehicle) StopAirConditioning() error {
+	return v.StopAirConditioningContext(context.Background())
+}
+
+// StopAirConditioningContext is like StopAirConditioning but uses ctx for the request
+func (v Vehicle) StopAirConditioningContext(ctx context.Context) error {
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/auto_conditioning_stop"
-	_, err := sendCommand(apiUrl, nil)
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// SetSeatHeater sets the heating level of a seat, from 0 (off) to 3
+func (v Vehicle) SetSeatHeater(seat Seat, level int) error {
+	return v.SetSeatHeaterContext(context.Background(), seat, level)
+}
+
+// SetSeatHeaterContext is like SetSeatHeater but uses ctx for the request
+func (v Vehicle) SetSeatHeaterContext(ctx context.Context, seat Seat, level int) error {
+	switch seat {
+	case SeatDriver, SeatPassenger, SeatRearLeft, SeatRearCenter, SeatRearRight:
+	default:
+		return &ValidationError{Field: "heater", Value: seat, Reason: "unknown seat"}
+	}
+	if level < 0 || level > 3 {
+		return &ValidationError{Field: "level", Value: level, Reason: "must be between 0 and 3"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/remote_seat_heater_request"
+	body, _ := json.Marshal(&SeatHeaterRequest{Heater: seat, Level: level})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetSteeringWheelHeater turns the heated steering wheel on or off
+func (v Vehicle) SetSteeringWheelHeater(on bool) error {
+	return v.SetSteeringWheelHeaterContext(context.Background(), on)
+}
+
+// SetSteeringWheelHeaterContext is like SetSteeringWheelHeater but uses ctx for the request
+func (v Vehicle) SetSteeringWheelHeaterContext(ctx context.Context, on bool) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/remote_steering_wheel_heater_request"
+	body, _ := json.Marshal(&SwitchRequest{On: on})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetPreconditioningMax turns max defrost on or off, which heats the cabin
+// and defrosts the windows at full power
+func (v Vehicle) SetPreconditioningMax(on bool) error {
+	return v.SetPreconditioningMaxContext(context.Background(), on)
+}
+
+// SetPreconditioningMaxContext is like SetPreconditioningMax but uses ctx for the request
+func (v Vehicle) SetPreconditioningMaxContext(ctx context.Context, on bool) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_preconditioning_max"
+	body, _ := json.Marshal(&SwitchRequest{On: on})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetBioweaponDefenseMode turns bioweapon defense mode on or off
+func (v Vehicle) SetBioweaponDefenseMode(on bool) error {
+	return v.SetBioweaponDefenseModeContext(context.Background(), on)
+}
+
+// SetBioweaponDefenseModeContext is like SetBioweaponDefenseMode but uses ctx for the request
+func (v Vehicle) SetBioweaponDefenseModeContext(ctx context.Context, on bool) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_bioweapon_mode"
+	body, _ := json.Marshal(&BioweaponModeRequest{On: on, ManualOverride: true})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetClimateKeeperMode keeps the climate on after leaving the vehicle, in
+// keep, dog or camp mode, or turns climate keeper off
+func (v Vehicle) SetClimateKeeperMode(mode ClimateKeeperMode) error {
+	return v.SetClimateKeeperModeContext(context.Background(), mode)
+}
+
+// SetClimateKeeperModeContext is like SetClimateKeeperMode but uses ctx for the request
+func (v Vehicle) SetClimateKeeperModeContext(ctx context.Context, mode ClimateKeeperMode) error {
+	if mode < ClimateKeeperOff || mode > ClimateKeeperCamp {
+		return &ValidationError{Field: "climate_keeper_mode", Value: mode, Reason: "unknown mode"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_climate_keeper_mode"
+	body, _ := json.Marshal(&ClimateKeeperRequest{Mode: mode})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// SetCabinOverheatProtection turns cabin overheat protection on or off,
+// optionally only running the fan rather than the air conditioning
+func (v Vehicle) SetCabinOverheatProtection(on bool, fanOnly bool) error {
+	return v.SetCabinOverheatProtectionContext(context.Background(), on, fanOnly)
+}
+
+// SetCabinOverheatProtectionContext is like SetCabinOverheatProtection but uses ctx for the request
+func (v Vehicle) SetCabinOverheatProtectionContext(ctx context.Context, on bool, fanOnly bool) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/set_cabin_overheat_protection"
+	body, _ := json.Marshal(&CabinOverheatProtectionRequest{On: on, FanOnly: fanOnly})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// Toggles between playing and pausing the current media
+func (v Vehicle) MediaTogglePlayback() error {
+	return v.MediaTogglePlaybackContext(context.Background())
+}
+
+// MediaTogglePlaybackContext is like MediaTogglePlayback but uses ctx for the request
+func (v Vehicle) MediaTogglePlaybackContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_toggle_playback"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Skips to the next track
+func (v Vehicle) MediaNextTrack() error {
+	return v.MediaNextTrackContext(context.Background())
+}
+
+// MediaNextTrackContext is like MediaNextTrack but uses ctx for the request
+func (v Vehicle) MediaNextTrackContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_next_track"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Goes back to the previous track
+func (v Vehicle) MediaPrevTrack() error {
+	return v.MediaPrevTrackContext(context.Background())
+}
+
+// MediaPrevTrackContext is like MediaPrevTrack but uses ctx for the request
+func (v Vehicle) MediaPrevTrackContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_prev_track"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Skips to the next favorite
+func (v Vehicle) MediaNextFavorite() error {
+	return v.MediaNextFavoriteContext(context.Background())
+}
+
+// MediaNextFavoriteContext is like MediaNextFavorite but uses ctx for the request
+func (v Vehicle) MediaNextFavoriteContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_next_fav"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Goes back to the previous favorite
+func (v Vehicle) MediaPrevFavorite() error {
+	return v.MediaPrevFavoriteContext(context.Background())
+}
+
+// MediaPrevFavoriteContext is like MediaPrevFavorite but uses ctx for the request
+func (v Vehicle) MediaPrevFavoriteContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_prev_fav"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Turns the volume up by one step
+func (v Vehicle) MediaVolumeUp() error {
+	return v.MediaVolumeUpContext(context.Background())
+}
+
+// MediaVolumeUpContext is like MediaVolumeUp but uses ctx for the request
+func (v Vehicle) MediaVolumeUpContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_volume_up"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// Turns the volume down by one step
+func (v Vehicle) MediaVolumeDown() error {
+	return v.MediaVolumeDownContext(context.Background())
+}
+
+// MediaVolumeDownContext is like MediaVolumeDown but uses ctx for the request
+func (v Vehicle) MediaVolumeDownContext(ctx context.Context) error {
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/media_volume_down"
+	_, err := v.sendCommand(ctx, apiUrl, nil)
+	return err
+}
+
+// AdjustVolume sets the media volume to a level between 0 and 11
+func (v Vehicle) AdjustVolume(level float64) error {
+	return v.AdjustVolumeContext(context.Background(), level)
+}
+
+// AdjustVolumeContext is like AdjustVolume but uses ctx for the request
+func (v Vehicle) AdjustVolumeContext(ctx context.Context, level float64) error {
+	if level < 0 || level > maxVolume {
+		return &ValidationError{Field: "volume", Value: level, Reason: "must be between 0 and 11"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/adjust_volume"
+	body, _ := json.Marshal(&VolumeRequest{Volume: level})
+	_, err := v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
 // The desired state of the panoramic roof. The approximate percent open
 // values for each state are open = 100%, close = 0%, comfort = 80%, vent = %15, move = set %
-func (v Vehicle) MovePanoRoof(state string, percent int) error {
+func (v Vehicle) MovePanoRoof(state SunRoofState, percent int) error {
+	return v.MovePanoRoofContext(context.Background(), state, percent)
+}
+
+// MovePanoRoofContext is like MovePanoRoof but uses ctx for the request
+func (v Vehicle) MovePanoRoofContext(ctx context.Context, state SunRoofState, percent int) error {
+	switch state {
+	case SunRoofOpen, SunRoofClose, SunRoofComfort, SunRoofVent, SunRoofMove:
+	default:
+		return &ValidationError{Field: "state", Value: state, Reason: "unknown sun roof state"}
+	}
+	if percent < 0 || percent > 100 {
+		return &ValidationError{Field: "percent", Value: percent, Reason: "must be between 0 and 100"}
+	}
 	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/sun_roof_control"
-	theJson := `{"state": "` + state + `", "percent":` + strconv.Itoa(percent) + `}`
-	_, err := ActiveClient.post(apiUrl, []byte(theJson))
+	body, _ := json.Marshal(&SunRoofRequest{State: state, Percent: percent})
+	_, err := v.sendCommand(ctx, apiUrl, body)
+	return err
+}
+
+// WindowControl vents or closes all of the windows. Closing requires the
+// location of the user, which must be close to the vehicle.
+func (v Vehicle) WindowControl(command WindowCommand, lat float64, lon float64) error {
+	return v.WindowControlContext(context.Background(), command, lat, lon)
+}
+
+// WindowControlContext is like WindowControl but uses ctx for the request
+func (v Vehicle) WindowControlContext(ctx context.Context, command WindowCommand, lat float64, lon float64) error {
+	if command != WindowVent && command != WindowClose {
+		return &ValidationError{Field: "command", Value: command, Reason: "must be vent or close"}
+	}
+	if err := validateCoordinates(lat, lon); err != nil {
+		return err
+	}
+	if command == WindowClose && lat == 0 && lon == 0 {
+		return &ValidationError{Field: "lat", Value: lat, Reason: "closing requires the location of the user"}
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/window_control"
+	body, _ := json.Marshal(&WindowControlRequest{Command: command, Lat: lat, Lon: lon})
+	_, err := v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
 // Start starts the car by turning it on, requires the password to be sent
-// again
+// again. The password is sent in the body of the request, and
+// ErrRemoteStartUnsupported is returned if the vehicle state reports that
+// remote start is not supported.
 func (v Vehicle) Start(password string) error {
-	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/remote_start_drive?password=" + password
-	_, err := sendCommand(apiUrl, nil)
+	return v.StartContext(context.Background(), password)
+}
+
+// StartContext is like Start but uses ctx for the requests
+func (v Vehicle) StartContext(ctx context.Context, password string) error {
+	vehicleState, err := v.VehicleStateContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !vehicleState.RemoteStartSupported {
+		return ErrRemoteStartUnsupported
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/remote_start_drive"
+	body, _ := json.Marshal(&RemoteStartRequest{Password: password})
+	_, err = v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
-// Opens the trunk, where values may be 'front' or 'rear'
-func (v Vehicle) OpenTrunk(trunk string) error {
-	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/trunk_open" // ?which_trunk=" + trunk
-	theJson := `{"which_trunk": "` + trunk + `"}`
-	_, err := ActiveClient.post(apiUrl, []byte(theJson))
+// StartWithoutPassword starts the car for accounts that no longer require
+// the password to be sent again, such as those authorized with SSO tokens
+func (v Vehicle) StartWithoutPassword() error {
+	return v.StartContext(context.Background(), "")
+}
+
+// StartWithoutPasswordContext is like StartWithoutPassword but uses ctx for the requests
+func (v Vehicle) StartWithoutPasswordContext(ctx context.Context) error {
+	return v.StartContext(ctx, "")
+}
+
+// Opens the trunk, where values may be TrunkFront or TrunkRear. The state of
+// the trunk is read first, and nothing is sent if it is already open.
+func (v Vehicle) OpenTrunk(trunk Trunk) error {
+	return v.OpenTrunkContext(context.Background(), trunk)
+}
+
+// OpenTrunkContext is like OpenTrunk but uses ctx for the requests
+func (v Vehicle) OpenTrunkContext(ctx context.Context, trunk Trunk) error {
+	open, err := v.trunkOpen(ctx, trunk)
+	if err != nil || open {
+		return err
+	}
+	return v.ActuateTrunkContext(ctx, trunk)
+}
+
+// Opens the front trunk, if it is not already open
+func (v Vehicle) OpenFrunk() error {
+	return v.OpenTrunk(TrunkFront)
+}
+
+// OpenFrunkContext is like OpenFrunk but uses ctx for the requests
+func (v Vehicle) OpenFrunkContext(ctx context.Context) error {
+	return v.OpenTrunkContext(ctx, TrunkFront)
+}
+
+// Closes the rear trunk, if it is open. The front trunk can not be closed
+// remotely.
+func (v Vehicle) CloseTrunk(trunk Trunk) error {
+	return v.CloseTrunkContext(context.Background(), trunk)
+}
+
+// CloseTrunkContext is like CloseTrunk but uses ctx for the requests
+func (v Vehicle) CloseTrunkContext(ctx context.Context, trunk Trunk) error {
+	if trunk == TrunkFront {
+		return &ValidationError{Field: "which_trunk", Value: trunk, Reason: "the front trunk can not be closed remotely"}
+	}
+	open, err := v.trunkOpen(ctx, trunk)
+	if err != nil || !open {
+		return err
+	}
+	return v.ActuateTrunkContext(ctx, trunk)
+}
+
+// ActuateTrunk opens the trunk if it is closed, or closes it if it is open
+// and the vehicle has a powered liftgate. Use OpenTrunk or CloseTrunk to act
+// on the current state of the trunk.
+func (v Vehicle) ActuateTrunk(trunk Trunk) error {
+	return v.ActuateTrunkContext(context.Background(), trunk)
+}
+
+// ActuateTrunkContext is like ActuateTrunk but uses ctx for the request
+func (v Vehicle) ActuateTrunkContext(ctx context.Context, trunk Trunk) error {
+	if err := validateTrunk(trunk); err != nil {
+		return err
+	}
+	apiUrl := BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/command/actuate_trunk"
+	body, _ := json.Marshal(&TrunkRequest{WhichTrunk: trunk})
+	_, err := v.sendCommand(ctx, apiUrl, body)
 	return err
 }
 
-// Sends a command to the vehicle
-func sendCommand(url string, reqBody []byte) ([]byte, error) {
+// Reads from the vehicle state whether the trunk is open
+func (v Vehicle) trunkOpen(ctx context.Context, trunk Trunk) (bool, error) {
+	if err := validateTrunk(trunk); err != nil {
+		return false, err
+	}
+	vehicleState, err := v.VehicleStateContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if trunk == TrunkFront {
+		return vehicleState.Ft != 0, nil
+	}
+	return vehicleState.Rt != 0, nil
+}
+
+// Checks that the trunk is either the front or the rear trunk
+func validateTrunk(trunk Trunk) error {
+	if trunk != TrunkFront && trunk != TrunkRear {
+		return &ValidationError{Field: "which_trunk", Value: trunk, Reason: "must be front or rear"}
+	}
+	return nil
+}
+
+// Sends a command to the vehicle using the client it was fetched with
+func (v Vehicle) sendCommand(ctx context.Context, url string, reqBody []byte) ([]byte, error) {
+	return v.client().sendCommand(ctx, url, reqBody)
+}
 
-	body, err := ActiveClient.post(url, reqBody)
+// Sends a command to a vehicle and checks the result reported by the API
+func (c *Client) sendCommand(ctx context.Context, url string, reqBody []byte) ([]byte, error) {
+	body, err := c.post(ctx, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +1281,7 @@ func sendCommand(url string, reqBody []byte) ([]byte, error) {
 			return nil, err
 		}
 		if response.Response.Result != true && response.Response.Reason != "" {
-			return nil, errors.New(response.Response.Reason)
+			return nil, newCommandError(url, response.Response.Reason)
 		}
 	}
 	return body, nil
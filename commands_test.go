@@ -1,7 +1,14 @@
 package tesla
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -102,6 +109,55 @@ func TestCommandsSpec(t *testing.T) {
 		So(err, ShouldBeNil)
 	})
 
+	Convey("Should close the charge port", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.CloseChargePort()
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Should set the charging amps", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetChargingAmps(16)
+		So(err, ShouldBeNil)
+		err = vehicle.SetChargingAmps(48)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should schedule charging", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetScheduledCharging(true, 120)
+		So(err, ShouldBeNil)
+		err = vehicle.SetScheduledCharging(true, 1440)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should schedule the departure", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		departure := &ScheduledDeparture{
+			Enable:                      true,
+			DepartureTime:               450,
+			PreconditioningEnabled:      true,
+			OffPeakChargingEnabled:      true,
+			OffPeakChargingWeekdaysOnly: true,
+			EndOffPeakTime:              360,
+		}
+		err = vehicle.SetScheduledDeparture(departure)
+		So(err, ShouldBeNil)
+		departure.EndOffPeakTime = -1
+		err = vehicle.SetScheduledDeparture(departure)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.SetScheduledDeparture(nil)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
 	Convey("Should reset the valet pin", t, func() {
 		vehicles, err := client.Vehicles()
 		So(err, ShouldBeNil)
@@ -118,6 +174,14 @@ func TestCommandsSpec(t *testing.T) {
 		So(err, ShouldBeNil)
 	})
 
+	Convey("Should report a charge limit that is already set", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetChargeLimit(80)
+		So(errors.Is(err, ErrAlreadySet), ShouldBeTrue)
+	})
+
 	Convey("Should set the car to standard charge level", t, func() {
 		vehicles, err := client.Vehicles()
 		So(err, ShouldBeNil)
@@ -186,7 +250,70 @@ func TestCommandsSpec(t *testing.T) {
 		vehicles, err := client.Vehicles()
 		So(err, ShouldBeNil)
 		vehicle := vehicles[0]
-		err = vehicle.SetTemprature(72.0, 72.0)
+		err = vehicle.SetTemprature(22.0, 22.0)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Should reject a temprature out of the available range", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetTemprature(72.0, 22.0)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "invalid driver_temp 72: must be between 15 and 28")
+	})
+
+	Convey("Should set the seat heater", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetSeatHeater(SeatRearRight, 2)
+		So(err, ShouldBeNil)
+		err = vehicle.SetSeatHeater(SeatRearRight, 4)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.SetSeatHeater(Seat(3), 1)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should turn on the steering wheel heater", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetSteeringWheelHeater(true)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Should turn on max defrost", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetPreconditioningMax(true)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Should turn off bioweapon defense mode", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetBioweaponDefenseMode(false)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Should set the climate keeper to dog mode", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetClimateKeeperMode(ClimateKeeperDog)
+		So(err, ShouldBeNil)
+		err = vehicle.SetClimateKeeperMode(ClimateKeeperMode(7))
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should turn on cabin overheat protection with the fan only", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.SetCabinOverheatProtection(true, true)
 		So(err, ShouldBeNil)
 	})
 
@@ -196,6 +323,85 @@ func TestCommandsSpec(t *testing.T) {
 		vehicle := vehicles[0]
 		err = vehicle.Start("foo")
 		So(err, ShouldBeNil)
+		err = vehicle.StartWithoutPassword()
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Should control the media", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		So(vehicle.MediaTogglePlayback(), ShouldBeNil)
+		So(vehicle.MediaNextTrack(), ShouldBeNil)
+		So(vehicle.MediaPrevTrack(), ShouldBeNil)
+		So(vehicle.MediaNextFavorite(), ShouldBeNil)
+		So(vehicle.MediaPrevFavorite(), ShouldBeNil)
+		So(vehicle.MediaVolumeUp(), ShouldBeNil)
+		So(vehicle.MediaVolumeDown(), ShouldBeNil)
+	})
+
+	Convey("Should mute the media", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.AdjustVolume(0)
+		So(err, ShouldBeNil)
+		err = vehicle.AdjustVolume(12)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should send navigation destinations", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		So(vehicle.ShareAddress("3500 Deer Creek Road, Palo Alto, CA", ""), ShouldBeNil)
+		So(vehicle.ShareAddress("3500 Deer Creek Road, Palo Alto, CA", "de-DE"), ShouldBeNil)
+		So(vehicle.Navigate(&NavigationRequest{
+			Type:        NavigationShareRaw,
+			Value:       NavigationValue{Text: "3500 Deer Creek Road, Palo Alto, CA"},
+			Locale:      "en-US",
+			TimestampMs: "1577836800000",
+		}), ShouldBeNil)
+		So(vehicle.NavigateToCoordinates(37.4, -122.1), ShouldBeNil)
+		So(vehicle.NavigateToSupercharger(42), ShouldBeNil)
+		err = vehicle.ShareAddress("", "")
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.NavigateToCoordinates(91, 0)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should schedule and cancel a software update", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		So(vehicle.ScheduleSoftwareUpdate(2*time.Hour), ShouldBeNil)
+		So(vehicle.CancelSoftwareUpdate(), ShouldBeNil)
+		err = vehicle.ScheduleSoftwareUpdate(-time.Minute)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should toggle valet mode", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		So(vehicle.SetValetMode(true, "1234"), ShouldBeNil)
+		So(vehicle.SetValetMode(false, ""), ShouldBeNil)
+		err = vehicle.SetValetMode(true, "12a4")
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should control speed limit mode", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		So(vehicle.SpeedLimitActivate("1234"), ShouldBeNil)
+		So(vehicle.SpeedLimitDeactivate("1234"), ShouldBeNil)
+		So(vehicle.SpeedLimitClearPIN("1234"), ShouldBeNil)
+		So(vehicle.SpeedLimitSetLimit(70), ShouldBeNil)
+		err = vehicle.SpeedLimitActivate("123")
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.SpeedLimitSetLimit(100)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
 	})
 
 	Convey("Should move the Pano Roof around", t, func() {
@@ -218,8 +424,270 @@ func TestCommandsSpec(t *testing.T) {
 			err := vehicle.MovePanoRoof("close", 0)
 			So(err, ShouldBeNil)
 		})
+		Convey("Should reject an unknown state or percent", func() {
+			err := vehicle.MovePanoRoof("tilt", 0)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+			err = vehicle.MovePanoRoof(SunRoofMove, 101)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		})
+	})
+
+	Convey("Should control the windows", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		err = vehicle.WindowControl(WindowVent, 35.1, 20.2)
+		So(err, ShouldBeNil)
+		err = vehicle.WindowControl(WindowClose, 35.1, 20.2)
+		So(err, ShouldBeNil)
+		err = vehicle.WindowControl("open", 35.1, 20.2)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.WindowControl(WindowClose, 0, 0)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.WindowControl(WindowVent, 35.1, 200)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+	})
+
+	Convey("Should not send a command with a cancelled context", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		vehicle := vehicles[0]
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = vehicle.HonkHornContext(ctx)
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
 	})
 
 	AuthURL = previousAuthURL
 	BaseURL = previousURL
 }
+
+func TestWakeUpAndWaitSpec(t *testing.T) {
+	polls := 0
+	nullPolls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		asleep := strings.Replace(WakeupResponseJSON, `"state":"online"`, `"state":"asleep"`, 1)
+		switch req.URL.String() {
+		case "/api/1/vehicles/1234/wake_up":
+			w.Write([]byte(asleep))
+		case "/api/1/vehicles/1234":
+			polls++
+			if polls < 3 {
+				w.Write([]byte(asleep))
+				return
+			}
+			w.Write([]byte(WakeupResponseJSON))
+		case "/api/1/vehicles/5678/wake_up":
+			w.WriteHeader(408)
+		case "/api/1/vehicles/9012/wake_up":
+			w.Write([]byte(`{"response":null}`))
+		case "/api/1/vehicles/9012":
+			nullPolls++
+			w.Write([]byte(WakeupResponseJSON))
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+	previousInterval := wakeupPollInterval
+	wakeupPollInterval = time.Millisecond
+
+	Convey("Should poll until the vehicle is online", t, func() {
+		vehicle := newTestVehicle(1234)
+		awake, err := vehicle.WakeUpAndWait(context.Background(), time.Second)
+		So(err, ShouldBeNil)
+		So(awake.State, ShouldEqual, "online")
+		So(polls, ShouldEqual, 3)
+	})
+
+	Convey("Should poll the vehicle when the wake up response has none", t, func() {
+		vehicle := newTestVehicle(9012)
+		awake, err := vehicle.WakeUpAndWait(context.Background(), time.Second)
+		So(err, ShouldBeNil)
+		So(awake.State, ShouldEqual, "online")
+		So(nullPolls, ShouldEqual, 1)
+	})
+
+	Convey("Should time out when the vehicle stays unavailable", t, func() {
+		vehicle := newTestVehicle(5678)
+		_, err := vehicle.WakeUpAndWait(context.Background(), 20*time.Millisecond)
+		So(err, ShouldEqual, ErrWakeupTimeout)
+	})
+
+	Convey("Should stop when the context is cancelled", t, func() {
+		vehicle := newTestVehicle(5678)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := vehicle.WakeUpAndWait(ctx, time.Second)
+		So(err, ShouldEqual, context.Canceled)
+	})
+
+	BaseURL = previousURL
+	wakeupPollInterval = previousInterval
+}
+
+func TestTrunkSpec(t *testing.T) {
+	actuated := []Trunk{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/api/1/vehicles/1234/data_request/vehicle_state":
+			state := strings.Replace(VehicleStateJSON, `"ft":0`, `"ft":1`, 1)
+			w.Write([]byte(state))
+		case "/api/1/vehicles/1234/command/actuate_trunk":
+			trunkRequest := &TrunkRequest{}
+			json.NewDecoder(req.Body).Decode(trunkRequest)
+			actuated = append(actuated, trunkRequest.WhichTrunk)
+			w.Write([]byte(CommandResponseJSON))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	vehicle := newTestVehicle(1234)
+
+	Convey("Should not open a trunk that is already open", t, func() {
+		actuated = actuated[:0]
+		err := vehicle.OpenFrunk()
+		So(err, ShouldBeNil)
+		So(actuated, ShouldBeEmpty)
+	})
+
+	Convey("Should open a closed trunk", t, func() {
+		actuated = actuated[:0]
+		err := vehicle.OpenTrunk(TrunkRear)
+		So(err, ShouldBeNil)
+		So(actuated, ShouldResemble, []Trunk{TrunkRear})
+	})
+
+	Convey("Should not close a trunk that is already closed", t, func() {
+		actuated = actuated[:0]
+		err := vehicle.CloseTrunk(TrunkRear)
+		So(err, ShouldBeNil)
+		So(actuated, ShouldBeEmpty)
+	})
+
+	Convey("Should reject closing the front trunk before any request", t, func() {
+		actuated = actuated[:0]
+		err := vehicle.CloseTrunk(TrunkFront)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		err = vehicle.ActuateTrunk("boot")
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		So(actuated, ShouldBeEmpty)
+	})
+
+	BaseURL = previousURL
+}
+
+func TestSentryModeSpec(t *testing.T) {
+	requests := []bool{}
+	available := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/api/1/vehicles/1234/data_request/vehicle_state":
+			state := VehicleStateJSON
+			if !available {
+				state = strings.Replace(state, `"sentry_mode_available":true`, `"sentry_mode_available":false`, 1)
+			}
+			w.Write([]byte(state))
+		case "/api/1/vehicles/1234/command/set_sentry_mode":
+			sentryRequest := &SentryData{}
+			json.NewDecoder(req.Body).Decode(sentryRequest)
+			requests = append(requests, sentryRequest.On)
+			w.Write([]byte(CommandResponseJSON))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	vehicle := newTestVehicle(1234)
+
+	Convey("Should send sentry mode as a boolean", t, func() {
+		requests = requests[:0]
+		So(vehicle.EnableSentry(), ShouldBeNil)
+		So(vehicle.DisableSentry(), ShouldBeNil)
+		So(requests, ShouldResemble, []bool{true, false})
+	})
+
+	Convey("Should only change sentry mode when it differs", t, func() {
+		requests = requests[:0]
+		So(vehicle.EnsureSentryMode(false), ShouldBeNil)
+		So(requests, ShouldBeEmpty)
+		So(vehicle.EnsureSentryMode(true), ShouldBeNil)
+		So(requests, ShouldResemble, []bool{true})
+	})
+
+	Convey("Should not send a request when sentry mode is unavailable", t, func() {
+		requests = requests[:0]
+		available = false
+		err := vehicle.EnsureSentryMode(true)
+		So(err, ShouldEqual, ErrSentryModeUnavailable)
+		So(requests, ShouldBeEmpty)
+	})
+
+	BaseURL = previousURL
+}
+
+func TestRemoteStartUnsupportedSpec(t *testing.T) {
+	started := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/api/1/vehicles/1234/data_request/vehicle_state":
+			state := strings.Replace(VehicleStateJSON, `"remote_start_supported":true`, `"remote_start_supported":false`, 1)
+			w.Write([]byte(state))
+		case "/api/1/vehicles/1234/command/remote_start_drive":
+			started = true
+			w.Write([]byte(CommandResponseJSON))
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	Convey("Should not start a vehicle without remote start support", t, func() {
+		vehicle := newTestVehicle(1234)
+		err := vehicle.Start("foo")
+		So(err, ShouldEqual, ErrRemoteStartUnsupported)
+		So(started, ShouldBeFalse)
+	})
+
+	BaseURL = previousURL
+}
+
+func TestChargingAmpsWithoutMaxSpec(t *testing.T) {
+	requested := 0
+	fetched := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/api/1/vehicles/1234/data_request/charge_state":
+			fetched++
+			state := strings.Replace(ChargeStateJSON, `"charge_current_request_max":40`, `"charge_current_request_max":0`, 1)
+			w.Write([]byte(state))
+		case "/api/1/vehicles/1234/command/set_charging_amps":
+			requested++
+			w.Write([]byte(CommandResponseJSON))
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	vehicle := newTestVehicle(1234)
+
+	Convey("Should only check the lower bound when the vehicle reports no maximum", t, func() {
+		So(vehicle.SetChargingAmps(48), ShouldBeNil)
+		So(requested, ShouldEqual, 1)
+		So(fetched, ShouldEqual, 1)
+		err := vehicle.SetChargingAmps(0)
+		So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		So(requested, ShouldEqual, 1)
+		So(fetched, ShouldEqual, 1)
+	})
+
+	BaseURL = previousURL
+}
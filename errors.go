@@ -0,0 +1,166 @@
+package tesla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Sentinel errors to test API and command failures against with errors.Is
+var (
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrVehicleUnavailable = errors.New("vehicle unavailable")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrServer             = errors.New("server error")
+	ErrCommandFailed      = errors.New("command failed")
+	ErrAlreadySet         = errors.New("already set")
+	ErrNotCharging        = errors.New("not charging")
+	ErrWakeupTimeout      = errors.New("timed out waiting for the vehicle to wake up")
+	ErrVehicleNotFound    = errors.New("vehicle not found")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrStreamClosed       = errors.New("HTTP stream closed")
+	ErrBadStreamMessage   = errors.New("Bad message from Tesla API stream")
+
+	ErrSentryModeUnavailable  = errors.New("sentry mode is not available on the vehicle")
+	ErrRemoteStartUnsupported = errors.New("remote start is not supported by the vehicle")
+)
+
+// APIError is returned when the Tesla API responds with a non 200 status
+type APIError struct {
+	StatusCode       int         `json:"-"`
+	Status           string      `json:"-"`
+	Method           string      `json:"-"`
+	Endpoint         string      `json:"-"`
+	Header           http.Header `json:"-"`
+	Body             []byte      `json:"-"`
+	ErrorCode        string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// The types of errors reported by the websocket stream
+const (
+	StreamErrorVehicleDisconnected = "vehicle_disconnected"
+	StreamErrorVehicleError        = "vehicle_error"
+	StreamErrorClientError         = "client_error"
+)
+
+// StreamError is sent on the error channel of a websocket stream when Tesla
+// reports an error, after which the stream ends
+type StreamError struct {
+	Type    string
+	Message string
+}
+
+// CommandError is returned when the vehicle rejects a command, Reason holds
+// the reason given by the API such as "already_set" or "not_charging"
+type CommandError struct {
+	Command string
+	Reason  string
+}
+
+// ValidationError is returned, before any request is sent, when an argument
+// of a command is out of range or not supported by the vehicle
+type ValidationError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+}
+
+// Builds the APIError for a failed response, parsing the error details
+// from the body when it is JSON
+func newAPIError(req *http.Request, res *http.Response, body []byte) *APIError {
+	apiError := &APIError{}
+	json.Unmarshal(body, apiError)
+	apiError.StatusCode = res.StatusCode
+	apiError.Status = res.Status
+	apiError.Method = req.Method
+	apiError.Endpoint = req.URL.Path
+	apiError.Header = res.Header
+	apiError.Body = body
+	return apiError
+}
+
+func (e *APIError) Error() string {
+	msg := e.Status
+	if e.ErrorCode != "" {
+		msg += ": " + e.ErrorCode
+	}
+	if e.ErrorDescription != "" {
+		msg += " (" + e.ErrorDescription + ")"
+	}
+	return msg
+}
+
+// Is reports whether the error matches one of the sentinel errors for its
+// status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrVehicleUnavailable:
+		return e.StatusCode == http.StatusRequestTimeout
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// Builds the CommandError for a command rejected by the vehicle
+func newCommandError(apiUrl string, reason string) *CommandError {
+	command := apiUrl
+	if u, err := url.Parse(apiUrl); err == nil {
+		command = path.Base(u.Path)
+	}
+	return &CommandError{Command: command, Reason: reason}
+}
+
+func (e *CommandError) Error() string {
+	return e.Reason
+}
+
+// Is reports whether the error matches ErrCommandFailed, or a more specific
+// sentinel error for its reason
+func (e *CommandError) Is(target error) bool {
+	switch target {
+	case ErrCommandFailed:
+		return true
+	case ErrAlreadySet:
+		return strings.HasPrefix(e.Reason, "already_")
+	case ErrNotCharging:
+		return e.Reason == "not_charging"
+	}
+	return false
+}
+
+func (e *StreamError) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// Is reports whether the error matches ErrStreamClosed, or a more specific
+// sentinel error for its type
+func (e *StreamError) Is(target error) bool {
+	switch target {
+	case ErrStreamClosed:
+		return true
+	case ErrVehicleUnavailable:
+		return e.Type == StreamErrorVehicleDisconnected
+	case ErrUnauthorized:
+		return e.Type == StreamErrorClientError && strings.HasPrefix(e.Message, "Can't validate token")
+	}
+	return false
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s %v: %s", e.Field, e.Value, e.Reason)
+}
+
+// Is reports whether the error matches ErrInvalidArgument
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
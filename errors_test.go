@@ -0,0 +1,81 @@
+package tesla
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	VehicleUnavailableJSON = `{"response":null,"error":"vehicle unavailable: {:error=>\"vehicle unavailable:\"}","error_description":""}`
+	NotChargingJSON        = `{"response":{"reason":"not_charging","result":false}}`
+)
+
+func TestErrorsSpec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/api/1/vehicles/1234/data_request/charge_state":
+			w.WriteHeader(408)
+			w.Write([]byte(VehicleUnavailableJSON))
+		case "/api/1/vehicles/1234/data_request/climate_state":
+			w.WriteHeader(401)
+		case "/api/1/vehicles/1234/data_request/drive_state":
+			w.WriteHeader(429)
+		case "/api/1/vehicles/1234/data_request/gui_settings":
+			w.WriteHeader(503)
+		case "/api/1/vehicles/1234/command/charge_stop":
+			w.Write([]byte(NotChargingJSON))
+		case "/api/1/vehicles/1234/command/charge_standard":
+			w.Write([]byte(ChargeAlreadySetJSON))
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	vehicle := newTestVehicle(1234)
+
+	Convey("Should return an API error for an unavailable vehicle", t, func() {
+		_, err := vehicle.ChargeState()
+		So(errors.Is(err, ErrVehicleUnavailable), ShouldBeTrue)
+		So(errors.Is(err, ErrUnauthorized), ShouldBeFalse)
+		var apiError *APIError
+		So(errors.As(err, &apiError), ShouldBeTrue)
+		So(apiError.StatusCode, ShouldEqual, 408)
+		So(apiError.Method, ShouldEqual, "GET")
+		So(apiError.Endpoint, ShouldEqual, "/api/1/vehicles/1234/data_request/charge_state")
+		So(apiError.ErrorCode, ShouldStartWith, "vehicle unavailable")
+		So(string(apiError.Body), ShouldEqual, VehicleUnavailableJSON)
+		So(err.Error(), ShouldStartWith, "408 Request Timeout: vehicle unavailable")
+	})
+
+	Convey("Should match API errors by status code", t, func() {
+		_, err := vehicle.ClimateState()
+		So(errors.Is(err, ErrUnauthorized), ShouldBeTrue)
+		_, err = vehicle.DriveState()
+		So(errors.Is(err, ErrRateLimited), ShouldBeTrue)
+		_, err = vehicle.GuiSettings()
+		So(errors.Is(err, ErrServer), ShouldBeTrue)
+		So(errors.Is(err, ErrRateLimited), ShouldBeFalse)
+	})
+
+	Convey("Should return command errors with the reason", t, func() {
+		err := vehicle.StopCharging()
+		So(errors.Is(err, ErrCommandFailed), ShouldBeTrue)
+		So(errors.Is(err, ErrNotCharging), ShouldBeTrue)
+		So(errors.Is(err, ErrAlreadySet), ShouldBeFalse)
+		var commandError *CommandError
+		So(errors.As(err, &commandError), ShouldBeTrue)
+		So(commandError.Command, ShouldEqual, "charge_stop")
+		So(commandError.Reason, ShouldEqual, "not_charging")
+
+		err = vehicle.SetChargeLimitStandard()
+		So(errors.Is(err, ErrAlreadySet), ShouldBeTrue)
+		So(err.Error(), ShouldEqual, "already_standard")
+	})
+
+	BaseURL = previousURL
+}
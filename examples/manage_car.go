@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -51,10 +52,10 @@ func main() {
 	fmt.Println(vehicle.StopAirConditioning())
 	fmt.Println(vehicle.UnlockDoors())
 	fmt.Println(vehicle.LockDoors())
-	fmt.Println(vehicle.SetTemprature(72.0, 72.0))
+	fmt.Println(vehicle.SetTemprature(22.0, 22.0))
 	fmt.Println(vehicle.Start(os.Getenv("TESLA_PASSWORD")))
 	fmt.Println(vehicle.OpenTrunk("rear"))
-	fmt.Println(vehicle.OpenTrunk("front"))
+	fmt.Println(vehicle.OpenFrunk())
 	fmt.Println(vehicle.MovePanoRoof("vent", 0))
 	fmt.Println(vehicle.MovePanoRoof("open", 0))
 	fmt.Println(vehicle.MovePanoRoof("move", 50))
@@ -66,28 +67,21 @@ func main() {
 	fmt.Println(vehicle.AutoparkReverse())
 	// Take care with these, as the car will move
 
-	// Stream vehicle events
-	eventChan, errChan, err := vehicle.Stream()
-	if err != nil {
-		fmt.Println(err)
-		return
-	} else {
-		for {
-			select {
-			case event := <-eventChan:
-				eventJSON, _ := json.Marshal(event)
-				fmt.Println(string(eventJSON))
-			case err = <-errChan:
-				fmt.Println(err)
-				if err.Error() == "HTTP stream closed" {
-					fmt.Println("Reconnecting!")
-					eventChan, errChan, err = vehicle.Stream()
-					if err != nil {
-						fmt.Println(err)
-						return
-					}
-				}
-			}
+	// Stream vehicle events, reconnecting whenever the stream ends
+	supervisor := tesla.NewStreamSupervisor(vehicle.Vehicle)
+	go func() {
+		for err := range supervisor.Errors() {
+			fmt.Println(err)
+		}
+	}()
+	go func() {
+		for status := range supervisor.Status() {
+			fmt.Println("Stream", status)
 		}
+	}()
+	go supervisor.Run(context.Background())
+	for event := range supervisor.Events() {
+		eventJSON, _ := json.Marshal(event)
+		fmt.Println(string(eventJSON))
 	}
 }
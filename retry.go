@@ -0,0 +1,158 @@
+package tesla
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes which failed requests are retried and how long to
+// wait between attempts
+type RetryPolicy struct {
+	// Maximum number of attempts for a request, including the first one
+	MaxAttempts int
+	// Delay before the first retry, doubled for every further attempt
+	BaseDelay time.Duration
+	// Upper bound of the delay between attempts. A request is not retried
+	// when the API asks for a longer wait through Retry-After.
+	MaxDelay time.Duration
+	// Response status codes that are retried, network errors are always
+	// retried
+	StatusCodes []int
+	// HTTP methods that are retried
+	Methods []string
+	// Commands that are not idempotent, such as honk_horn or
+	// trigger_homelink, are only retried when they are listed here
+	Commands []string
+}
+
+// Commands that have a visible effect every time they are sent, or that
+// toggle state, so repeating them after an ambiguous failure is unsafe
+var nonIdempotentCommands = map[string]bool{
+	"actuate_trunk":         true,
+	"autopark_request":      true,
+	"flash_lights":          true,
+	"honk_horn":             true,
+	"media_next_fav":        true,
+	"media_next_track":      true,
+	"media_prev_fav":        true,
+	"media_prev_track":      true,
+	"media_toggle_playback": true,
+	"media_volume_down":     true,
+	"media_volume_up":       true,
+	"remote_start_drive":    true,
+	"trigger_homelink":      true,
+}
+
+// DefaultRetryPolicy returns a policy retrying transient failures up to
+// three times
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		StatusCodes: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		Methods: []string{"GET", "POST", "PUT", "DELETE"},
+	}
+}
+
+// Indicates whether the request should be attempted again after err
+func (p *RetryPolicy) shouldRetry(req *http.Request, err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return false
+	}
+	if !containsString(p.Methods, req.Method) {
+		return false
+	}
+	command := path.Base(req.URL.Path)
+	if nonIdempotentCommands[command] && !containsString(p.Commands, command) {
+		return false
+	}
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		return true
+	}
+	if p.MaxDelay > 0 && parseRetryAfter(apiError.Header.Get("Retry-After")) > p.MaxDelay {
+		return false
+	}
+	for _, code := range p.StatusCodes {
+		if apiError.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns how long to wait before the next attempt, using exponential
+// backoff with jitter, or the Retry-After header of the response if it asks
+// for a longer wait
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	backoff := backoffDelay(p.BaseDelay, p.MaxDelay, attempt)
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		if retryAfter := parseRetryAfter(apiError.Header.Get("Retry-After")); retryAfter > backoff {
+			return retryAfter
+		}
+	}
+	return backoff
+}
+
+// Returns the exponential backoff for the attempt, doubling base for every
+// attempt up to max, with jitter in the upper half of the delay
+func backoffDelay(base time.Duration, max time.Duration, attempt int) time.Duration {
+	backoff := base << uint(attempt-1)
+	if backoff < base || (max > 0 && backoff > max) {
+		backoff = max
+	}
+	if backoff > 0 {
+		backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	}
+	return backoff
+}
+
+// Parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// Waits for d, returning early with the context error if ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Indicates whether the slice contains the string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package tesla
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetrySpec(t *testing.T) {
+	attempts := map[string]int{}
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		attempts[req.URL.Path]++
+		switch req.URL.Path {
+		case "/api/1/vehicles":
+			if attempts[req.URL.Path] < 3 {
+				w.WriteHeader(503)
+				return
+			}
+			w.Write([]byte(VehiclesJSON))
+		case "/api/1/vehicles/1234/command/set_charge_limit":
+			bodies = append(bodies, string(body))
+			if attempts[req.URL.Path] < 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(429)
+				return
+			}
+			w.Write([]byte(CommandResponseJSON))
+		case "/api/1/vehicles/1234/command/honk_horn":
+			w.WriteHeader(408)
+		case "/api/1/vehicles/1234/data_request/charge_state":
+			w.WriteHeader(404)
+		case "/api/1/vehicles/1234/data_request/drive_state":
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(429)
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	client := newTestClient()
+	client.Retry = policy
+	vehicle := &Vehicle{ID: 1234, c: client}
+
+	Convey("Should retry transient server errors", t, func() {
+		vehicles, err := client.Vehicles()
+		So(err, ShouldBeNil)
+		So(vehicles[0].DisplayName, ShouldEqual, "Macak")
+		So(attempts["/api/1/vehicles"], ShouldEqual, 3)
+	})
+
+	Convey("Should resend the request body when rate limited", t, func() {
+		err := vehicle.SetChargeLimit(50)
+		So(err, ShouldBeNil)
+		So(bodies, ShouldResemble, []string{`{"percent":50}`, `{"percent":50}`})
+	})
+
+	Convey("Should not retry errors that are not transient", t, func() {
+		_, err := vehicle.ChargeState()
+		So(err, ShouldNotBeNil)
+		So(attempts["/api/1/vehicles/1234/data_request/charge_state"], ShouldEqual, 1)
+	})
+
+	Convey("Should not wait longer than the maximum delay asked by Retry-After", t, func() {
+		_, err := vehicle.DriveState()
+		var apiError *APIError
+		So(errors.As(err, &apiError), ShouldBeTrue)
+		So(apiError.StatusCode, ShouldEqual, 429)
+		So(attempts["/api/1/vehicles/1234/data_request/drive_state"], ShouldEqual, 1)
+	})
+
+	Convey("Should only retry commands that are not idempotent when opted in", t, func() {
+		err := vehicle.HonkHorn()
+		So(err, ShouldNotBeNil)
+		So(attempts["/api/1/vehicles/1234/command/honk_horn"], ShouldEqual, 1)
+
+		policy.Commands = []string{"honk_horn"}
+		err = vehicle.HonkHorn()
+		So(err, ShouldNotBeNil)
+		So(attempts["/api/1/vehicles/1234/command/honk_horn"], ShouldEqual, 4)
+	})
+
+	Convey("Should make a single attempt without a retry policy", t, func() {
+		attempts["/api/1/vehicles"] = 0
+		client.Retry = nil
+		_, err := client.Vehicles()
+		So(err, ShouldNotBeNil)
+		So(attempts["/api/1/vehicles"], ShouldEqual, 1)
+	})
+
+	BaseURL = previousURL
+}
+
+func TestRetryDelaySpec(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	Convey("Should back off exponentially with jitter up to the maximum delay", t, func() {
+		So(policy.delay(1, nil), ShouldBeBetweenOrEqual, 500*time.Millisecond, time.Second)
+		So(policy.delay(2, nil), ShouldBeBetweenOrEqual, time.Second, 2*time.Second)
+		So(policy.delay(10, nil), ShouldBeBetweenOrEqual, 2*time.Second, 4*time.Second)
+	})
+
+	Convey("Should honor Retry-After when it asks for a longer wait", t, func() {
+		err := &APIError{StatusCode: 429, Header: http.Header{"Retry-After": []string{"3"}}}
+		So(policy.delay(1, err), ShouldEqual, 3*time.Second)
+		So(parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)), ShouldBeGreaterThan, 58*time.Second)
+		So(parseRetryAfter("soon"), ShouldEqual, 0)
+	})
+}
@@ -1,9 +1,12 @@
 package tesla
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"strconv"
-	"log"
+	"sync"
+	"time"
 )
 
 // Contains the current charge states that exist within the vehicle
@@ -37,7 +40,7 @@ type ChargeState struct {
 	ChargeRate                  float64     `json:"charge_rate"`
 	ChargePortDoorOpen          bool        `json:"charge_port_door_open"`
 	MotorizedChargePort         bool        `json:"motorized_charge_port"`
-	ScheduledChargingStartTime  interface{} `json:"scheduled_charging_start_time"`
+	ScheduledChargingStartTime  *int64      `json:"scheduled_charging_start_time"`
 	ScheduledChargingPending    bool        `json:"scheduled_charging_pending"`
 	UserChargeEnableRequest     interface{} `json:"user_charge_enable_request"`
 	ChargeEnableRequest         bool        `json:"charge_enable_request"`
@@ -49,6 +52,33 @@ type ChargeState struct {
 	ManagedChargingActive       bool        `json:"managed_charging_active"`
 	ManagedChargingUserCanceled bool        `json:"managed_charging_user_canceled"`
 	ManagedChargingStartTime    interface{} `json:"managed_charging_start_time"`
+	ChargeAmps                  int         `json:"charge_amps"`
+	ScheduledChargingMode       string      `json:"scheduled_charging_mode"`
+	ScheduledDepartureTime      *int64      `json:"scheduled_departure_time"`
+	ScheduledDepartureMinutes   int         `json:"scheduled_departure_time_minutes"`
+	PreconditioningEnabled      bool        `json:"preconditioning_enabled"`
+	PreconditioningTimes        string      `json:"preconditioning_times"`
+	OffPeakChargingEnabled      bool        `json:"off_peak_charging_enabled"`
+	OffPeakChargingTimes        string      `json:"off_peak_charging_times"`
+	OffPeakHoursEndTime         int         `json:"off_peak_hours_end_time"`
+}
+
+// ScheduledChargingStart returns when scheduled charging starts, and false
+// if no charging is scheduled
+func (s *ChargeState) ScheduledChargingStart() (time.Time, bool) {
+	if s.ScheduledChargingStartTime == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(*s.ScheduledChargingStartTime, 0), true
+}
+
+// ScheduledDeparture returns the scheduled departure time, and false if no
+// departure is scheduled
+func (s *ChargeState) ScheduledDeparture() (time.Time, bool) {
+	if s.ScheduledDepartureTime == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(*s.ScheduledDepartureTime, 0), true
 }
 
 // Contains the current climate states availale from the vehicle
@@ -60,7 +90,7 @@ type ClimateState struct {
 	LeftTempDirection       float64     `json:"left_temp_direction"`
 	RightTempDirection      float64     `json:"right_temp_direction"`
 	IsAutoConditioningOn    bool        `json:"is_auto_conditioning_on"`
-	IsFrontDefrosterOn      bool         `json:"is_front_defroster_on"`
+	IsFrontDefrosterOn      bool        `json:"is_front_defroster_on"`
 	IsRearDefrosterOn       bool        `json:"is_rear_defroster_on"`
 	FanStatus               interface{} `json:"fan_status"`
 	IsClimateOn             bool        `json:"is_climate_on"`
@@ -74,6 +104,12 @@ type ClimateState struct {
 	SeatHeaterRearRightBack int         `json:"seat_heater_rear_right_back"`
 	SeatHeaterRearLeftBack  int         `json:"seat_heater_rear_left_back"`
 	SmartPreconditioning    bool        `json:"smart_preconditioning"`
+	IsPreconditioning       bool        `json:"is_preconditioning"`
+	DefrostMode             int         `json:"defrost_mode"`
+	SteeringWheelHeater     bool        `json:"steering_wheel_heater"`
+	BioweaponMode           bool        `json:"bioweapon_mode"`
+	ClimateKeeperMode       string      `json:"climate_keeper_mode"`
+	CabinOverheatProtection string      `json:"cabin_overheat_protection"`
 }
 
 // Contains the current drive state of the vehicle
@@ -95,48 +131,110 @@ type GuiSettings struct {
 	GuiRangeDisplay     string `json:"gui_range_display"`
 }
 
+// Contains the current media state of the vehicle
+type MediaState struct {
+	RemoteControlEnabled bool `json:"remote_control_enabled"`
+}
+
+// Contains the speed limit mode settings of the vehicle
+type SpeedLimitMode struct {
+	Active          bool    `json:"active"`
+	CurrentLimitMph float64 `json:"current_limit_mph"`
+	MaxLimitMph     float64 `json:"max_limit_mph"`
+	MinLimitMph     float64 `json:"min_limit_mph"`
+	PinCodeSet      bool    `json:"pin_code_set"`
+}
+
+// Contains the state of a software update for the vehicle. Status is empty
+// when no update is available, and otherwise one of "available",
+// "scheduled", "downloading", "downloading_wifi_wait" or "installing".
+type SoftwareUpdate struct {
+	Status              string `json:"status"`
+	Version             string `json:"version"`
+	DownloadPerc        int    `json:"download_perc"`
+	InstallPerc         int    `json:"install_perc"`
+	ExpectedDurationSec int    `json:"expected_duration_sec"`
+	ScheduledTimeMs     int64  `json:"scheduled_time_ms"`
+}
+
 // Contains the current state of the vehicle
 type VehicleState struct {
-	APIVersion              int     `json:"api_version"`
-	AutoParkState           string  `json:"autopark_state"`
-	AutoParkStateV2         string  `json:"autopark_state_v2"`
-	CalendarSupported       bool    `json:"calendar_supported"`
-	CarType                 string  `json:"car_type"`
-	CarVersion              string  `json:"car_version"`
-	CenterDisplayState      int     `json:"center_display_state"`
-	DarkRims                bool    `json:"dark_rims"`
-	Df                      int     `json:"df"`
-	Dr                      int     `json:"dr"`
-	ExteriorColor           string  `json:"exterior_color"`
-	Ft                      int     `json:"ft"`
-	HasSpoiler              bool    `json:"has_spoiler"`
-	Locked                  bool    `json:"locked"`
-	NotificationsSupported  bool    `json:"notifications_supported"`
-	Odometer                float64 `json:"odometer"`
-	ParsedCalendarSupported bool    `json:"parsed_calendar_supported"`
-	PerfConfig              string  `json:"perf_config"`
-	Pf                      int     `json:"pf"`
-	Pr                      int     `json:"pr"`
-	RearSeatHeaters         int     `json:"rear_seat_heaters"`
-	RemoteStart             bool    `json:"remote_start"`
-	RemoteStartSupported    bool    `json:"remote_start_supported"`
-	Rhd                     bool    `json:"rhd"`
-	RoofColor               string  `json:"roof_color"`
-	Rt                      int     `json:"rt"`
-	SentryMode              bool    `json:"sentry_mode"`
-	SentryModeAvailable     bool    `json:"sentry_mode_available"`
-	SeatType                int     `json:"seat_type"`
-	SpoilerType             string  `json:"spoiler_type"`
-	SunRoofInstalled        int     `json:"sun_roof_installed"`
-	SunRoofPercentOpen      int     `json:"sun_roof_percent_open"`
-	SunRoofState            string  `json:"sun_roof_state"`
-	ThirdRowSeats           string  `json:"third_row_seats"`
-	ValetMode               bool    `json:"valet_mode"`
-	VehicleName             string  `json:"vehicle_name"`
-	WheelType               string  `json:"wheel_type"`
-}
-
-// Represents the request to get the states of the vehicle
+	APIVersion              int             `json:"api_version"`
+	AutoParkState           string          `json:"autopark_state"`
+	AutoParkStateV2         string          `json:"autopark_state_v2"`
+	CalendarSupported       bool            `json:"calendar_supported"`
+	CarType                 string          `json:"car_type"`
+	CarVersion              string          `json:"car_version"`
+	CenterDisplayState      int             `json:"center_display_state"`
+	DarkRims                bool            `json:"dark_rims"`
+	Df                      int             `json:"df"`
+	Dr                      int             `json:"dr"`
+	ExteriorColor           string          `json:"exterior_color"`
+	Ft                      int             `json:"ft"`
+	HasSpoiler              bool            `json:"has_spoiler"`
+	Locked                  bool            `json:"locked"`
+	MediaState              *MediaState     `json:"media_state"`
+	NotificationsSupported  bool            `json:"notifications_supported"`
+	Odometer                float64         `json:"odometer"`
+	ParsedCalendarSupported bool            `json:"parsed_calendar_supported"`
+	PerfConfig              string          `json:"perf_config"`
+	Pf                      int             `json:"pf"`
+	Pr                      int             `json:"pr"`
+	RearSeatHeaters         int             `json:"rear_seat_heaters"`
+	RemoteStart             bool            `json:"remote_start"`
+	RemoteStartSupported    bool            `json:"remote_start_supported"`
+	Rhd                     bool            `json:"rhd"`
+	RoofColor               string          `json:"roof_color"`
+	Rt                      int             `json:"rt"`
+	SentryMode              bool            `json:"sentry_mode"`
+	SentryModeAvailable     bool            `json:"sentry_mode_available"`
+	SeatType                int             `json:"seat_type"`
+	SoftwareUpdate          *SoftwareUpdate `json:"software_update"`
+	SpeedLimitMode          *SpeedLimitMode `json:"speed_limit_mode"`
+	SpoilerType             string          `json:"spoiler_type"`
+	SunRoofInstalled        int             `json:"sun_roof_installed"`
+	SunRoofPercentOpen      int             `json:"sun_roof_percent_open"`
+	SunRoofState            string          `json:"sun_roof_state"`
+	ThirdRowSeats           string          `json:"third_row_seats"`
+	ValetMode               bool            `json:"valet_mode"`
+	ValetPinNeeded          bool            `json:"valet_pin_needed"`
+	VehicleName             string          `json:"vehicle_name"`
+	WheelType               string          `json:"wheel_type"`
+}
+
+// Contains the configuration of the vehicle, describing the hardware and
+// options it was built with
+type VehicleConfig struct {
+	CanAcceptNavigationRequests bool   `json:"can_accept_navigation_requests"`
+	CanActuateTrunks            bool   `json:"can_actuate_trunks"`
+	CarSpecialType              string `json:"car_special_type"`
+	CarType                     string `json:"car_type"`
+	ChargePortType              string `json:"charge_port_type"`
+	DefaultChargeToMax          bool   `json:"default_charge_to_max"`
+	EuVehicle                   bool   `json:"eu_vehicle"`
+	ExteriorColor               string `json:"exterior_color"`
+	HasAirSuspension            bool   `json:"has_air_suspension"`
+	HasLudicrousMode            bool   `json:"has_ludicrous_mode"`
+	HasSeatCooling              bool   `json:"has_seat_cooling"`
+	MotorizedChargePort         bool   `json:"motorized_charge_port"`
+	Plg                         bool   `json:"plg"`
+	RearSeatHeaters             int    `json:"rear_seat_heaters"`
+	RearSeatType                int    `json:"rear_seat_type"`
+	Rhd                         bool   `json:"rhd"`
+	RoofColor                   string `json:"roof_color"`
+	SeatType                    int    `json:"seat_type"`
+	SpoilerType                 string `json:"spoiler_type"`
+	SunRoofInstalled            int    `json:"sun_roof_installed"`
+	ThirdRowSeats               string `json:"third_row_seats"`
+	Timestamp                   int64  `json:"timestamp"`
+	TrimBadging                 string `json:"trim_badging"`
+	UseRangeBadging             bool   `json:"use_range_badging"`
+	WheelType                   string `json:"wheel_type"`
+}
+
+// Represents the request to get the states of the vehicle. The vehicle
+// config shares field names with the other states, so it is kept in its own
+// field rather than embedded.
 type StateRequest struct {
 	Response struct {
 		*ChargeState
@@ -144,6 +242,7 @@ type StateRequest struct {
 		*DriveState
 		*GuiSettings
 		*VehicleState
+		VehicleConfig *VehicleConfig `json:"vehicle_config"`
 	} `json:"response"`
 }
 
@@ -154,7 +253,12 @@ type Response struct {
 
 // MobileEnabled returns if the vehicle is mobile enabled for Tesla API control
 func (v *Vehicle) MobileEnabled() (bool, error) {
-	body, err := ActiveClient.get(BaseURL + "/vehicles/" + strconv.FormatInt(v.ID, 10) + "/mobile_enabled")
+	return v.MobileEnabledContext(context.Background())
+}
+
+// MobileEnabledContext is like MobileEnabled but uses ctx for the request
+func (v *Vehicle) MobileEnabledContext(ctx context.Context) (bool, error) {
+	body, err := v.client().get(ctx, BaseURL+"/vehicles/"+strconv.FormatInt(v.ID, 10)+"/mobile_enabled")
 	if err != nil {
 		return false, err
 	}
@@ -168,7 +272,12 @@ func (v *Vehicle) MobileEnabled() (bool, error) {
 
 // ChargeState returns the charge state of the vehicle
 func (v *Vehicle) ChargeState() (*ChargeState, error) {
-	stateRequest, err := fetchState("/charge_state", v.ID)
+	return v.ChargeStateContext(context.Background())
+}
+
+// ChargeStateContext is like ChargeState but uses ctx for the request
+func (v *Vehicle) ChargeStateContext(ctx context.Context) (*ChargeState, error) {
+	stateRequest, err := v.fetchState(ctx, "/charge_state", v.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +286,12 @@ func (v *Vehicle) ChargeState() (*ChargeState, error) {
 
 // ClimateState returns the climate state of the vehicle
 func (v Vehicle) ClimateState() (*ClimateState, error) {
-	stateRequest, err := fetchState("/climate_state", v.ID)
+	return v.ClimateStateContext(context.Background())
+}
+
+// ClimateStateContext is like ClimateState but uses ctx for the request
+func (v Vehicle) ClimateStateContext(ctx context.Context) (*ClimateState, error) {
+	stateRequest, err := v.fetchState(ctx, "/climate_state", v.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +299,12 @@ func (v Vehicle) ClimateState() (*ClimateState, error) {
 }
 
 func (v Vehicle) DriveState() (*DriveState, error) {
-	stateRequest, err := fetchState("/drive_state", v.ID)
+	return v.DriveStateContext(context.Background())
+}
+
+// DriveStateContext is like DriveState but uses ctx for the request
+func (v Vehicle) DriveStateContext(ctx context.Context) (*DriveState, error) {
+	stateRequest, err := v.fetchState(ctx, "/drive_state", v.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +313,12 @@ func (v Vehicle) DriveState() (*DriveState, error) {
 
 // GuiSettings returns the GUI settings of the vehicle
 func (v Vehicle) GuiSettings() (*GuiSettings, error) {
-	stateRequest, err := fetchState("/gui_settings", v.ID)
+	return v.GuiSettingsContext(context.Background())
+}
+
+// GuiSettingsContext is like GuiSettings but uses ctx for the request
+func (v Vehicle) GuiSettingsContext(ctx context.Context) (*GuiSettings, error) {
+	stateRequest, err := v.fetchState(ctx, "/gui_settings", v.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -202,82 +326,153 @@ func (v Vehicle) GuiSettings() (*GuiSettings, error) {
 }
 
 func (v Vehicle) VehicleState() (*VehicleState, error) {
-	stateRequest, err := fetchState("/vehicle_state", v.ID)
+	return v.VehicleStateContext(context.Background())
+}
+
+// VehicleStateContext is like VehicleState but uses ctx for the request
+func (v Vehicle) VehicleStateContext(ctx context.Context) (*VehicleState, error) {
+	stateRequest, err := v.fetchState(ctx, "/vehicle_state", v.ID)
 	if err != nil {
 		return nil, err
 	}
 	return stateRequest.Response.VehicleState, nil
 }
 
-// A utility function to fetch the appropriate state of the vehicle
-func fetchState(resource string, id int64) (*StateRequest, error) {
-	stateRequest := &StateRequest{}
-	body, err := ActiveClient.get(BaseURL + "/vehicles/" + strconv.FormatInt(id, 10) + "/data_request" + resource)
+// VehicleConfig returns the configuration of the vehicle
+func (v Vehicle) VehicleConfig() (*VehicleConfig, error) {
+	return v.VehicleConfigContext(context.Background())
+}
+
+// VehicleConfigContext is like VehicleConfig but uses ctx for the request
+func (v Vehicle) VehicleConfigContext(ctx context.Context) (*VehicleConfig, error) {
+	configResponse := &struct {
+		Response *VehicleConfig `json:"response"`
+	}{}
+	body, err := v.client().get(ctx, BaseURL+"/vehicles/"+strconv.FormatInt(v.ID, 10)+"/data_request/vehicle_config")
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(body, stateRequest)
+	err = json.Unmarshal(body, configResponse)
 	if err != nil {
 		return nil, err
 	}
-	return stateRequest, nil
+	return configResponse.Response, nil
 }
 
-// Data : Get data of the vehicle (calling this will not permit the car to sleep)
-func (v Vehicle) Data(vid int64) (*StateRequest, error) {
-
-	log.Println("Retreiving vehicle data") 
+// A utility function to fetch the appropriate state of the vehicle
+func (v Vehicle) fetchState(ctx context.Context, resource string, id int64) (*StateRequest, error) {
 	stateRequest := &StateRequest{}
-
-	/*log.Println(BaseURL + "/vehicles/" + strconv.FormatInt(vid, 10) + "/vehicle_data")
-	body, err := ActiveClient.get(BaseURL + "/vehicles/" + strconv.FormatInt(vid, 10) + "/vehicle_data")
+	body, err := v.client().get(ctx, BaseURL+"/vehicles/"+strconv.FormatInt(id, 10)+"/data_request"+resource)
 	if err != nil {
 		return nil, err
 	}
 	err = json.Unmarshal(body, stateRequest)
 	if err != nil {
 		return nil, err
-	}*/
+	}
+	return stateRequest, nil
+}
 
-	// climate_state
-	stateRequestClimate, err := fetchState("/climate_state", v.ID)
-	if err != nil {
-		log.Println("Error getting climate_state")
-		return nil, err
+// Data : Get data of the vehicle (calling this will not permit the car to sleep).
+// The vid argument is ignored, the ID of the vehicle is used instead.
+func (v Vehicle) Data(vid int64) (*StateRequest, error) {
+	return v.DataContext(context.Background())
+}
+
+// DataContext is like Data but uses ctx for the requests. All of the states
+// are fetched at once from the vehicle_data endpoint. If that endpoint fails
+// by itself, such as with a server error or a response that can not be
+// decoded, the states are fetched concurrently from the endpoints of the
+// individual states instead.
+func (v Vehicle) DataContext(ctx context.Context) (*StateRequest, error) {
+	stateRequest, err := v.vehicleData(ctx)
+	if err == nil || ctx.Err() != nil || !fallBackToStates(err) {
+		return stateRequest, err
 	}
-	stateRequest.Response.ClimateState = stateRequestClimate.Response.ClimateState
+	return v.dataByState(ctx)
+}
 
-	// drive_state
-	stateRequestGui, err := fetchState("/drive_state", v.ID)
-	if err != nil {
-		log.Println("Error getting drive_state")
-		return nil, err
+// Indicates whether the states may be fetched individually after the
+// vehicle_data endpoint failed with err. Errors that would fail those
+// requests as well, such as an asleep vehicle or rate limiting, are returned
+// as is.
+func fallBackToStates(err error) bool {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return !errors.Is(err, ErrUnauthorized) &&
+			!errors.Is(err, ErrVehicleUnavailable) &&
+			!errors.Is(err, ErrRateLimited)
 	}
-	stateRequest.Response.DriveState = stateRequestGui.Response.DriveState
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	return errors.As(err, &syntaxError) || errors.As(err, &typeError)
+}
+
+// The response of the vehicle_data endpoint, which nests each of the states
+type vehicleDataResponse struct {
+	Response struct {
+		ChargeState   *ChargeState   `json:"charge_state"`
+		ClimateState  *ClimateState  `json:"climate_state"`
+		DriveState    *DriveState    `json:"drive_state"`
+		GuiSettings   *GuiSettings   `json:"gui_settings"`
+		VehicleState  *VehicleState  `json:"vehicle_state"`
+		VehicleConfig *VehicleConfig `json:"vehicle_config"`
+	} `json:"response"`
+}
 
-	// gui_settings
-	stateRequestSettings, err := fetchState("/gui_settings", v.ID)
+// Fetches all of the states with a single call to the vehicle_data endpoint
+func (v Vehicle) vehicleData(ctx context.Context) (*StateRequest, error) {
+	body, err := v.client().get(ctx, BaseURL+"/vehicles/"+strconv.FormatInt(v.ID, 10)+"/vehicle_data")
 	if err != nil {
-		log.Println("Error getting gui_settings")
 		return nil, err
 	}
-	stateRequest.Response.GuiSettings = stateRequestSettings.Response.GuiSettings
-
-	// vehicle_state
-	stateRequestVehicle, err := fetchState("/vehicle_state", v.ID)
+	dataResponse := &vehicleDataResponse{}
+	err = json.Unmarshal(body, dataResponse)
 	if err != nil {
-		log.Println("Error getting vehicle_state")
 		return nil, err
 	}
-	stateRequest.Response.VehicleState = stateRequestVehicle.Response.VehicleState
+	stateRequest := &StateRequest{}
+	stateRequest.Response.ChargeState = dataResponse.Response.ChargeState
+	stateRequest.Response.ClimateState = dataResponse.Response.ClimateState
+	stateRequest.Response.DriveState = dataResponse.Response.DriveState
+	stateRequest.Response.GuiSettings = dataResponse.Response.GuiSettings
+	stateRequest.Response.VehicleState = dataResponse.Response.VehicleState
+	stateRequest.Response.VehicleConfig = dataResponse.Response.VehicleConfig
+	return stateRequest, nil
+}
 
-	// charge_state
-	stateRequestCharge, err := fetchState("/charge_state", v.ID)
-	if err != nil {
-		log.Println("Error getting charge_state")
-		return nil, err
+// Fetches each of the states from its own endpoint concurrently
+func (v Vehicle) dataByState(ctx context.Context) (*StateRequest, error) {
+	resources := []string{"/charge_state", "/climate_state", "/drive_state", "/gui_settings", "/vehicle_state"}
+	results := make([]*StateRequest, len(resources))
+	errs := make([]error, len(resources)+1)
+	var config *VehicleConfig
+	var wg sync.WaitGroup
+	for i, resource := range resources {
+		wg.Add(1)
+		go func(i int, resource string) {
+			defer wg.Done()
+			results[i], errs[i] = v.fetchState(ctx, resource, v.ID)
+		}(i, resource)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		config, errs[len(resources)] = v.VehicleConfigContext(ctx)
+	}()
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
 	}
-	stateRequest.Response.ChargeState = stateRequestCharge.Response.ChargeState
 
+	stateRequest := &StateRequest{}
+	stateRequest.Response.ChargeState = results[0].Response.ChargeState
+	stateRequest.Response.ClimateState = results[1].Response.ClimateState
+	stateRequest.Response.DriveState = results[2].Response.DriveState
+	stateRequest.Response.GuiSettings = results[3].Response.GuiSettings
+	stateRequest.Response.VehicleState = results[4].Response.VehicleState
+	stateRequest.Response.VehicleConfig = config
 	return stateRequest, nil
 }
@@ -1,20 +1,40 @@
 package tesla
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 var (
-	TrueJSON         = `{"response":true}`
-	ChargeStateJSON  = `{"response":{"charging_state":"Complete","charge_limit_soc":90,"charge_limit_soc_std":90,"charge_limit_soc_min":50,"charge_limit_soc_max":100,"charge_to_max_range":false,"battery_heater_on":null,"not_enough_power_to_heat":null,"max_range_charge_counter":0,"fast_charger_present":null,"fast_charger_type":"\u003Cinvalid\u003E","battery_range":235.92,"est_battery_range":200.46,"ideal_battery_range":304.73,"battery_level":90,"usable_battery_level":90,"battery_current":null,"charge_energy_added":19.94,"charge_miles_added_rated":64.5,"charge_miles_added_ideal":83.0,"charger_voltage":null,"charger_pilot_current":null,"charger_actual_current":null,"charger_power":null,"time_to_full_charge":0.0,"trip_charging":null,"charge_rate":0.0,"charge_port_door_open":null,"motorized_charge_port":true,"scheduled_charging_start_time":null,"scheduled_charging_pending":false,"user_charge_enable_request":null,"charge_enable_request":true,"eu_vehicle":false,"charger_phases":null,"charge_port_latch":"\u003Cinvalid\u003E","charge_current_request":40,"charge_current_request_max":40,"managed_charging_active":false,"managed_charging_user_canceled":false,"managed_charging_start_time":null}}`
-	ClimateStateJSON = `{"response":{"inside_temp":null,"outside_temp":null,"driver_temp_setting":22.0,"passenger_temp_setting":22.0,"left_temp_direction":17,"right_temp_direction":17,"is_auto_conditioning_on":null,"is_front_defroster_on":null,"is_rear_defroster_on":false,"fan_status":null,"is_climate_on":false,"min_avail_temp":15,"max_avail_temp":28,"seat_heater_left":0,"seat_heater_right":0,"seat_heater_rear_left":0,"seat_heater_rear_right":0,"seat_heater_rear_center":0,"seat_heater_rear_right_back":0,"seat_heater_rear_left_back":0,"smart_preconditioning":false}}`
-	DriveStateJSON   = `{"response":{"shift_state":null,"speed":null,"latitude":35.1,"longitude":20.2,"heading":57,"gps_as_of":1452491619}}`
-	GuiSettingsJSON  = `{"response":{"gui_distance_units":"mi/hr","gui_temperature_units":"F","gui_charge_rate_units":"mi/hr","gui_24_hour_time":true,"gui_range_display":"Rated"}}`
-	VehicleStateJSON = `{"response":{"api_version":3,"calendar_supported":true,"car_type":"s","car_version":"2.9.12","center_display_state":0,"dark_rims":false,"df":0,"dr":0,"exterior_color":"Black","ft":0,"has_spoiler":true,"locked":true,"notifications_supported":true,"odometer":3738.84633,"parsed_calendar_supported":true,"perf_config":"P2","pf":0,"pr":0,"rear_seat_heaters":1,"remote_start":false,"remote_start_supported":true,"rhd":false,"roof_color":"None","rt":0,"seat_type":1,"sun_roof_installed":2,"sun_roof_percent_open":0,"sun_roof_state":"unknown","third_row_seats":"None","valet_mode":false,"vehicle_name":"Macak","wheel_type":"Super21Gray"}}`
+	TrueJSON          = `{"response":true}`
+	ChargeStateJSON   = `{"response":{"charging_state":"Complete","charge_limit_soc":90,"charge_limit_soc_std":90,"charge_limit_soc_min":50,"charge_limit_soc_max":100,"charge_to_max_range":false,"battery_heater_on":null,"not_enough_power_to_heat":null,"max_range_charge_counter":0,"fast_charger_present":null,"fast_charger_type":"\u003Cinvalid\u003E","battery_range":235.92,"est_battery_range":200.46,"ideal_battery_range":304.73,"battery_level":90,"usable_battery_level":90,"battery_current":null,"charge_energy_added":19.94,"charge_miles_added_rated":64.5,"charge_miles_added_ideal":83.0,"charger_voltage":null,"charger_pilot_current":null,"charger_actual_current":null,"charger_power":null,"time_to_full_charge":0.0,"trip_charging":null,"charge_rate":0.0,"charge_port_door_open":null,"motorized_charge_port":true,"scheduled_charging_start_time":null,"scheduled_charging_pending":false,"user_charge_enable_request":null,"charge_enable_request":true,"eu_vehicle":false,"charger_phases":null,"charge_port_latch":"\u003Cinvalid\u003E","charge_current_request":40,"charge_current_request_max":40,"managed_charging_active":false,"managed_charging_user_canceled":false,"managed_charging_start_time":null}}`
+	ClimateStateJSON  = `{"response":{"inside_temp":null,"outside_temp":null,"driver_temp_setting":22.0,"passenger_temp_setting":22.0,"left_temp_direction":17,"right_temp_direction":17,"is_auto_conditioning_on":null,"is_front_defroster_on":null,"is_rear_defroster_on":false,"fan_status":null,"is_climate_on":false,"min_avail_temp":15,"max_avail_temp":28,"seat_heater_left":0,"seat_heater_right":0,"seat_heater_rear_left":0,"seat_heater_rear_right":0,"seat_heater_rear_center":0,"seat_heater_rear_right_back":0,"seat_heater_rear_left_back":0,"smart_preconditioning":false}}`
+	DriveStateJSON    = `{"response":{"shift_state":null,"speed":null,"latitude":35.1,"longitude":20.2,"heading":57,"gps_as_of":1452491619}}`
+	GuiSettingsJSON   = `{"response":{"gui_distance_units":"mi/hr","gui_temperature_units":"F","gui_charge_rate_units":"mi/hr","gui_24_hour_time":true,"gui_range_display":"Rated"}}`
+	VehicleConfigJSON = `{"response":{"can_accept_navigation_requests":true,"can_actuate_trunks":true,"car_special_type":"base","car_type":"models2","charge_port_type":"US","eu_vehicle":false,"exterior_color":"Black","has_air_suspension":true,"has_ludicrous_mode":false,"has_seat_cooling":false,"motorized_charge_port":true,"plg":true,"rear_seat_heaters":1,"rear_seat_type":0,"rhd":false,"roof_color":"None","seat_type":1,"spoiler_type":"None","sun_roof_installed":2,"third_row_seats":"None","timestamp":1452491619000,"trim_badging":"p90d","use_range_badging":false,"wheel_type":"Super21Gray"}}`
+	VehicleStateJSON  = `{"response":{"api_version":3,"calendar_supported":true,"car_type":"s","car_version":"2.9.12","center_display_state":0,"dark_rims":false,"df":0,"dr":0,"exterior_color":"Black","ft":0,"has_spoiler":true,"locked":true,"media_state":{"remote_control_enabled":true},"notifications_supported":true,"odometer":3738.84633,"parsed_calendar_supported":true,"perf_config":"P2","pf":0,"pr":0,"rear_seat_heaters":1,"remote_start":false,"remote_start_supported":true,"rhd":false,"roof_color":"None","rt":0,"seat_type":1,"software_update":{"download_perc":100,"expected_duration_sec":2700,"install_perc":1,"scheduled_time_ms":0,"status":"available","version":"2020.48.10"},"sentry_mode":false,"sentry_mode_available":true,"speed_limit_mode":{"active":false,"current_limit_mph":65.0,"max_limit_mph":90,"min_limit_mph":50,"pin_code_set":true},"sun_roof_installed":2,"sun_roof_percent_open":0,"sun_roof_state":"unknown","third_row_seats":"None","valet_mode":false,"vehicle_name":"Macak","wheel_type":"Super21Gray"}}`
 )
 
+// Nests the JSON of each state below its key, as the vehicle_data endpoint does
+func vehicleDataJSON() string {
+	section := func(json string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(json, `{"response":`), "}")
+	}
+	return `{"response":{"id":1234,"vehicle_id":456,"state":"online",` +
+		`"charge_state":` + section(ChargeStateJSON) + `,` +
+		`"climate_state":` + section(ClimateStateJSON) + `,` +
+		`"drive_state":` + section(DriveStateJSON) + `,` +
+		`"gui_settings":` + section(GuiSettingsJSON) + `,` +
+		`"vehicle_state":` + section(VehicleStateJSON) + `,` +
+		`"vehicle_config":` + section(VehicleConfigJSON) + `}}`
+}
+
 func TestStatesSpec(t *testing.T) {
 	ts := serveHTTP(t)
 	defer ts.Close()
@@ -48,6 +68,8 @@ func TestStatesSpec(t *testing.T) {
 		So(status.BatteryLevel, ShouldEqual, 90)
 		So(status.ChargeRate, ShouldEqual, 0)
 		So(status.ChargingState, ShouldEqual, "Complete")
+		_, scheduled := status.ScheduledChargingStart()
+		So(scheduled, ShouldBeFalse)
 	})
 
 	Convey("Should get climate state", t, func() {
@@ -86,8 +108,110 @@ func TestStatesSpec(t *testing.T) {
 		So(status.APIVersion, ShouldEqual, 3)
 		So(status.CalendarSupported, ShouldBeTrue)
 		So(status.Rt, ShouldEqual, 0)
+		So(status.MediaState.RemoteControlEnabled, ShouldBeTrue)
+		So(status.SpeedLimitMode.CurrentLimitMph, ShouldEqual, 65)
+		So(status.SpeedLimitMode.PinCodeSet, ShouldBeTrue)
+		So(status.SoftwareUpdate.Status, ShouldEqual, "available")
+		So(status.SoftwareUpdate.Version, ShouldEqual, "2020.48.10")
+		So(status.SoftwareUpdate.DownloadPerc, ShouldEqual, 100)
+		So(status.SoftwareUpdate.ExpectedDurationSec, ShouldEqual, 2700)
+	})
+
+	Convey("Should get Vehicle config", t, func() {
+		vehicles, err := client.Vehicles()
+		vehicle := vehicles[0]
+		config, err := vehicle.VehicleConfig()
+		So(err, ShouldBeNil)
+		So(config.CarType, ShouldEqual, "models2")
+		So(config.CanActuateTrunks, ShouldBeTrue)
+		So(config.Plg, ShouldBeTrue)
+		So(config.ChargePortType, ShouldEqual, "US")
+	})
+
+	Convey("Should get all of the vehicle data at once", t, func() {
+		vehicles, err := client.Vehicles()
+		vehicle := vehicles[0]
+		data, err := vehicle.Data(vehicle.ID)
+		So(err, ShouldBeNil)
+		So(data.Response.ChargeState.BatteryLevel, ShouldEqual, 90)
+		So(data.Response.ClimateState.DriverTempSetting, ShouldEqual, 22.0)
+		So(data.Response.DriveState.Latitude, ShouldEqual, 35.1)
+		So(data.Response.GuiSettings.GuiTemperatureUnits, ShouldEqual, "F")
+		So(data.Response.VehicleState.CarVersion, ShouldEqual, "2.9.12")
+		So(data.Response.VehicleConfig.TrimBadging, ShouldEqual, "p90d")
 	})
 
 	AuthURL = previousAuthURL
 	BaseURL = previousURL
 }
+
+func TestScheduledChargingSpec(t *testing.T) {
+	Convey("Should read the scheduled charging and departure times", t, func() {
+		chargeState := &ChargeState{}
+		err := json.Unmarshal([]byte(`{"scheduled_charging_start_time":1600000000,"scheduled_departure_time":1600020000}`), chargeState)
+		So(err, ShouldBeNil)
+		start, scheduled := chargeState.ScheduledChargingStart()
+		So(scheduled, ShouldBeTrue)
+		So(start.Unix(), ShouldEqual, 1600000000)
+		departure, scheduled := chargeState.ScheduledDeparture()
+		So(scheduled, ShouldBeTrue)
+		So(departure.Unix(), ShouldEqual, 1600020000)
+	})
+}
+
+func TestDataFallbackSpec(t *testing.T) {
+	requests := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests <- req.URL.Path
+		switch req.URL.Path {
+		case "/api/1/vehicles/1234/vehicle_data":
+			w.WriteHeader(500)
+		case "/api/1/vehicles/5678/vehicle_data":
+			w.WriteHeader(408)
+		case "/api/1/vehicles/1234/data_request/charge_state":
+			w.Write([]byte(ChargeStateJSON))
+		case "/api/1/vehicles/1234/data_request/climate_state":
+			w.Write([]byte(ClimateStateJSON))
+		case "/api/1/vehicles/1234/data_request/drive_state":
+			w.Write([]byte(DriveStateJSON))
+		case "/api/1/vehicles/1234/data_request/gui_settings":
+			w.Write([]byte(GuiSettingsJSON))
+		case "/api/1/vehicles/1234/data_request/vehicle_state":
+			w.Write([]byte(VehicleStateJSON))
+		case "/api/1/vehicles/1234/data_request/vehicle_config":
+			w.Write([]byte(VehicleConfigJSON))
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	vehicle := newTestVehicle(1234)
+
+	Convey("Should fall back to the individual states when vehicle_data fails", t, func() {
+		data, err := vehicle.Data(vehicle.ID)
+		So(err, ShouldBeNil)
+		So(len(requests), ShouldEqual, 7)
+		So(data.Response.ChargeState.BatteryLevel, ShouldEqual, 90)
+		So(data.Response.ClimateState.DriverTempSetting, ShouldEqual, 22.0)
+		So(data.Response.DriveState.Latitude, ShouldEqual, 35.1)
+		So(data.Response.GuiSettings.GuiTemperatureUnits, ShouldEqual, "F")
+		So(data.Response.VehicleState.CarVersion, ShouldEqual, "2.9.12")
+		So(data.Response.VehicleConfig.TrimBadging, ShouldEqual, "p90d")
+	})
+
+	Convey("Should not fall back when the vehicle is unavailable", t, func() {
+		unavailable := newTestVehicle(5678)
+		for len(requests) > 0 {
+			<-requests
+		}
+		_, err := unavailable.Data(unavailable.ID)
+		So(errors.Is(err, ErrVehicleUnavailable), ShouldBeTrue)
+		var apiError *APIError
+		So(errors.As(err, &apiError), ShouldBeTrue)
+		So(apiError.Endpoint, ShouldEqual, "/api/1/vehicles/5678/vehicle_data")
+		So(len(requests), ShouldEqual, 1)
+	})
+
+	BaseURL = previousURL
+}
@@ -2,92 +2,310 @@ package tesla
 
 import (
 	"bufio"
+	"context"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// The fields that may be requested from a stream with WithStreamFields
+const (
+	StreamFieldSpeed           = "speed"
+	StreamFieldOdometer        = "odometer"
+	StreamFieldSoc             = "soc"
+	StreamFieldElevation       = "elevation"
+	StreamFieldEstHeading      = "est_heading"
+	StreamFieldEstLat          = "est_lat"
+	StreamFieldEstLng          = "est_lng"
+	StreamFieldPower           = "power"
+	StreamFieldShiftState      = "shift_state"
+	StreamFieldRange           = "range"
+	StreamFieldEstRange        = "est_range"
+	StreamFieldHeading         = "heading"
+	StreamFieldNativeLatitude  = "native_latitude"
+	StreamFieldNativeLongitude = "native_longitude"
+	StreamFieldNativePower     = "native_power"
+	StreamFieldNativeType      = "native_type"
+)
+
 var (
+	// StreamParams are the comma separated fields requested from streams
+	// that are not given WithStreamFields
 	StreamParams = "speed,odometer,soc,elevation,est_heading,est_lat,est_lng,power,shift_state,range,est_range,heading"
 	StreamingURL = "https://streaming.vn.teslamotors.com"
+
+	// StreamingWebsocketURL is the endpoint of streams requested with
+	// WithWebsocket
+	StreamingWebsocketURL = "wss://streaming.vn.teslamotors.com/streaming/"
+
+	errNoStreamingToken = errors.New("no streaming token available for the vehicle")
 )
 
-// The event returned by the vehicle by the Tesla API
+// The event returned by the vehicle by the Tesla API. Fields that were not
+// requested, or that the vehicle left empty such as the shift state while
+// parked, are nil.
 type StreamEvent struct {
 	Timestamp  time.Time `json:"timestamp"`
-	Speed      int       `json:"speed"`
-	Odometer   float64   `json:"odometer"`
-	Soc        int       `json:"soc"`
-	Elevation  int       `json:"elevation"`
-	EstHeading int       `json:"est_heading"`
-	EstLat     float64   `json:"est_lat"`
-	EstLng     float64   `json:"est_lng"`
-	Power      int       `json:"power"`
-	ShiftState string    `json:"shift_state"`
-	Range      int       `json:"range"`
-	EstRange   int       `json:"est_range"`
-	Heading    int       `json:"heading"`
+	Speed      *int      `json:"speed"`
+	Odometer   *float64  `json:"odometer"`
+	Soc        *int      `json:"soc"`
+	Elevation  *int      `json:"elevation"`
+	EstHeading *int      `json:"est_heading"`
+	EstLat     *float64  `json:"est_lat"`
+	EstLng     *float64  `json:"est_lng"`
+	Power      *int      `json:"power"`
+	ShiftState *string   `json:"shift_state"`
+	Range      *int      `json:"range"`
+	EstRange   *int      `json:"est_range"`
+	Heading    *int      `json:"heading"`
+
+	NativeLatitude  *float64 `json:"native_latitude"`
+	NativeLongitude *float64 `json:"native_longitude"`
+	NativePower     *int     `json:"native_power"`
+	NativeType      *string  `json:"native_type"`
+
+	// Extra holds the values of requested fields that have no field of
+	// their own in the event, keyed by the name of the field
+	Extra map[string]string `json:"extra,omitempty"`
+
+	// Raw is the line the event was parsed from
+	Raw string `json:"-"`
+
+	// Errors holds the fields whose values could not be parsed, those
+	// fields are left nil
+	Errors []*StreamFieldError `json:"-"`
+}
+
+// StreamFieldError describes a field of a stream event whose value could not
+// be parsed
+type StreamFieldError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *StreamFieldError) Error() string {
+	return "invalid value " + strconv.Quote(e.Value) + " for stream field " + e.Field + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the conversion of the value
+func (e *StreamFieldError) Unwrap() error {
+	return e.Err
+}
+
+// StreamOption configures a stream requested with Stream or StreamContext
+type StreamOption func(*streamConfig)
+
+// The settings of a stream, as set by its options
+type streamConfig struct {
+	websocket bool
+	fields    []string
+}
+
+// WithWebsocket streams over the websocket protocol at
+// StreamingWebsocketURL, authorized with the OAuth token of the client,
+// instead of the legacy HTTP stream
+func WithWebsocket() StreamOption {
+	return func(config *streamConfig) {
+		config.websocket = true
+	}
+}
+
+// WithStreamFields requests the fields, in order, instead of the fields in
+// StreamParams. Fields without a field of their own in StreamEvent are kept
+// in its Extra map.
+func WithStreamFields(fields ...string) StreamOption {
+	return func(config *streamConfig) {
+		config.fields = fields
+	}
+}
+
+// Applies the options to the default stream settings
+func newStreamConfig(opts []StreamOption) *streamConfig {
+	config := &streamConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if len(config.fields) == 0 {
+		config.fields = strings.Split(StreamParams, ",")
+	}
+	return config
 }
 
 // Requests a stream from the vehicle and returns a Go channel
-func (v Vehicle) Stream() (chan *StreamEvent, chan error, error) {
-	url := StreamingURL + "/stream/" + strconv.Itoa(v.VehicleID) + "/?values=" + StreamParams
-	req, _ := http.NewRequest("GET", url, nil)
-	req.SetBasicAuth(ActiveClient.Auth.Email, v.Tokens[0])
-	resp, err := ActiveClient.HTTP.Do(req)
+func (v Vehicle) Stream(opts ...StreamOption) (chan *StreamEvent, chan error, error) {
+	return v.StreamContext(context.Background(), opts...)
+}
+
+// StreamContext is like Stream but the stream is closed, and the reading
+// goroutine exits, once ctx is cancelled. An *APIError is returned when the
+// stream is refused, such as when the streaming token of the vehicle has
+// expired.
+func (v Vehicle) StreamContext(ctx context.Context, opts ...StreamOption) (chan *StreamEvent, chan error, error) {
+	config := newStreamConfig(opts)
+	client := v.client()
+	if client == nil {
+		return nil, nil, errNoClient
+	}
+	if config.websocket {
+		return v.streamWebsocket(ctx, client, config)
+	}
+	url := StreamingURL + "/stream/" + strconv.Itoa(v.VehicleID) + "/?values=" + strings.Join(config.fields, ",")
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(v.Tokens) == 0 {
+		return nil, nil, errNoStreamingToken
+	}
+	req.SetBasicAuth(client.Auth.Email, v.Tokens[0])
+	resp, err := client.HTTP.Do(req)
 
 	if err != nil {
 		return nil, nil, err
 	}
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, nil, newAPIError(req, resp, body)
+	}
 
 	eventChan := make(chan *StreamEvent)
 	errChan := make(chan error)
-	go readStream(resp, eventChan, errChan)
+	go readStream(ctx, resp, config.fields, eventChan, errChan)
 
 	return eventChan, errChan, nil
 }
 
-// Reads the stream itself from the vehicle
-func readStream(resp *http.Response, eventChan chan *StreamEvent, errChan chan error) {
+// Reads the stream itself from the vehicle, until the stream ends or ctx is
+// cancelled
+func readStream(ctx context.Context, resp *http.Response, fields []string, eventChan chan *StreamEvent, errChan chan error) {
 	reader := bufio.NewReader(resp.Body)
 	scanner := bufio.NewScanner(reader)
 	scanner.Split(bufio.ScanLines)
 	defer resp.Body.Close()
 
 	for scanner.Scan() {
-		streamEvent, err := parseStreamEvent(scanner.Text())
+		streamEvent, err := parseStreamEvent(scanner.Text(), fields)
 		if err == nil {
-			eventChan <- streamEvent
+			select {
+			case eventChan <- streamEvent:
+			case <-ctx.Done():
+				return
+			}
 		} else {
-			errChan <- err
+			select {
+			case errChan <- err:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
-	errChan <- errors.New("HTTP stream closed")
+	if ctx.Err() != nil {
+		return
+	}
+	select {
+	case errChan <- ErrStreamClosed:
+	case <-ctx.Done():
+	}
 }
 
-// Parses the stream event, setting all of the appropriate data types
-func parseStreamEvent(event string) (*StreamEvent, error) {
+// Parses the stream event, setting all of the appropriate data types. The
+// columns following the timestamp hold the fields in the order they were
+// requested. Values that can not be parsed are recorded in the Errors of the
+// event rather than failing the whole event.
+func parseStreamEvent(event string, fields []string) (*StreamEvent, error) {
 	data := strings.Split(event, ",")
-	if len(data) != 13 {
-		return nil, errors.New("Bad message from Tesla API stream")
+	if len(data) != len(fields)+1 {
+		return nil, ErrBadStreamMessage
 	}
 
-	streamEvent := &StreamEvent{}
-	timestamp, _ := strconv.ParseInt(data[0], 10, 64)
+	streamEvent := &StreamEvent{Raw: event}
+	timestamp, err := strconv.ParseInt(data[0], 10, 64)
+	if err != nil {
+		streamEvent.Errors = append(streamEvent.Errors, &StreamFieldError{Field: "timestamp", Value: data[0], Err: err})
+	}
 	streamEvent.Timestamp = time.Unix(0, timestamp*int64(time.Millisecond))
-	streamEvent.Speed, _ = strconv.Atoi(data[1])
-	streamEvent.Odometer, _ = strconv.ParseFloat(data[2], 64)
-	streamEvent.Soc, _ = strconv.Atoi(data[3])
-	streamEvent.Elevation, _ = strconv.Atoi(data[4])
-	streamEvent.EstHeading, _ = strconv.Atoi(data[5])
-	streamEvent.EstLat, _ = strconv.ParseFloat(data[6], 64)
-	streamEvent.EstLng, _ = strconv.ParseFloat(data[7], 64)
-	streamEvent.Power, _ = strconv.Atoi(data[8])
-	streamEvent.ShiftState = data[9]
-	streamEvent.Range, _ = strconv.Atoi(data[10])
-	streamEvent.EstRange, _ = strconv.Atoi(data[11])
-	streamEvent.Heading, _ = strconv.Atoi(data[12])
+	for i, field := range fields {
+		value := data[i+1]
+		var err error
+		switch field {
+		case StreamFieldSpeed:
+			streamEvent.Speed, err = parseStreamInt(value)
+		case StreamFieldOdometer:
+			streamEvent.Odometer, err = parseStreamFloat(value)
+		case StreamFieldSoc:
+			streamEvent.Soc, err = parseStreamInt(value)
+		case StreamFieldElevation:
+			streamEvent.Elevation, err = parseStreamInt(value)
+		case StreamFieldEstHeading:
+			streamEvent.EstHeading, err = parseStreamInt(value)
+		case StreamFieldEstLat:
+			streamEvent.EstLat, err = parseStreamFloat(value)
+		case StreamFieldEstLng:
+			streamEvent.EstLng, err = parseStreamFloat(value)
+		case StreamFieldPower:
+			streamEvent.Power, err = parseStreamInt(value)
+		case StreamFieldShiftState:
+			streamEvent.ShiftState = parseStreamString(value)
+		case StreamFieldRange:
+			streamEvent.Range, err = parseStreamInt(value)
+		case StreamFieldEstRange:
+			streamEvent.EstRange, err = parseStreamInt(value)
+		case StreamFieldHeading:
+			streamEvent.Heading, err = parseStreamInt(value)
+		case StreamFieldNativeLatitude:
+			streamEvent.NativeLatitude, err = parseStreamFloat(value)
+		case StreamFieldNativeLongitude:
+			streamEvent.NativeLongitude, err = parseStreamFloat(value)
+		case StreamFieldNativePower:
+			streamEvent.NativePower, err = parseStreamInt(value)
+		case StreamFieldNativeType:
+			streamEvent.NativeType = parseStreamString(value)
+		default:
+			if streamEvent.Extra == nil {
+				streamEvent.Extra = map[string]string{}
+			}
+			streamEvent.Extra[field] = value
+		}
+		if err != nil {
+			streamEvent.Errors = append(streamEvent.Errors, &StreamFieldError{Field: field, Value: value, Err: err})
+		}
+	}
 	return streamEvent, nil
 }
+
+// Parses an integer column, an empty column is absent and yields nil
+func parseStreamInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// Parses a decimal column, an empty column is absent and yields nil
+func parseStreamFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Returns a string column, an empty column is absent and yields nil
+func parseStreamString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
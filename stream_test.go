@@ -1,7 +1,14 @@
 package tesla
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -32,13 +39,13 @@ func TestStreamSpec(t *testing.T) {
 		Convey("2 good, 1 bad", func() {
 			select {
 			case event := <-eventChan:
-				So(event.Speed, ShouldEqual, 65)
+				So(*event.Speed, ShouldEqual, 65)
 			case err = <-errChan:
 				So(err, ShouldBeNil)
 			}
 			select {
 			case event := <-eventChan:
-				So(event.Speed, ShouldEqual, 65)
+				So(*event.Speed, ShouldEqual, 65)
 			case err = <-errChan:
 				So(err, ShouldBeNil)
 			}
@@ -61,3 +68,104 @@ func TestStreamSpec(t *testing.T) {
 	BaseURL = previousURL
 	StreamingURL = previousStreamingURL
 }
+
+func TestStreamContextSpec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte(StreamEventString + "\n"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer ts.Close()
+	previousStreamingURL := StreamingURL
+	StreamingURL = ts.URL
+
+	client := &Client{Auth: &Auth{}, HTTP: &http.Client{}}
+	vehicle := &Vehicle{VehicleID: 123, Tokens: []string{"456", "789"}, c: client}
+
+	Convey("Should stop streaming once the context is cancelled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		eventChan, errChan, err := vehicle.StreamContext(ctx)
+		So(err, ShouldBeNil)
+		event := <-eventChan
+		So(*event.Speed, ShouldEqual, 65)
+		cancel()
+		select {
+		case event := <-eventChan:
+			So(event, ShouldBeNil)
+		case err := <-errChan:
+			So(err, ShouldBeNil)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	StreamingURL = previousStreamingURL
+}
+
+func TestStreamFieldsSpec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		Convey("Should request the selected fields", t, func() {
+			So(req.URL.Query().Get("values"), ShouldEqual, "power,shift_state,est_range,native_type,charger_power")
+		})
+		w.WriteHeader(200)
+		w.Write([]byte("1460905367,-12,D,184,wgs,7\n"))
+	}))
+	defer ts.Close()
+	previousStreamingURL := StreamingURL
+	StreamingURL = ts.URL
+
+	client := &Client{Auth: &Auth{}, HTTP: &http.Client{}}
+	vehicle := &Vehicle{VehicleID: 123, Tokens: []string{"456", "789"}, c: client}
+
+	Convey("Should map the columns by the selected fields", t, func() {
+		eventChan, _, err := vehicle.Stream(WithStreamFields(
+			StreamFieldPower, StreamFieldShiftState, StreamFieldEstRange, StreamFieldNativeType, "charger_power",
+		))
+		So(err, ShouldBeNil)
+		event := <-eventChan
+		So(*event.Power, ShouldEqual, -12)
+		So(*event.ShiftState, ShouldEqual, "D")
+		So(*event.EstRange, ShouldEqual, 184)
+		So(*event.NativeType, ShouldEqual, "wgs")
+		So(event.Speed, ShouldBeNil)
+		So(event.Extra, ShouldResemble, map[string]string{"charger_power": "7"})
+	})
+
+	Convey("Should reject a line that does not match the selected fields", t, func() {
+		_, err := parseStreamEvent(StreamEventString, []string{StreamFieldSpeed, StreamFieldOdometer})
+		So(err, ShouldEqual, ErrBadStreamMessage)
+	})
+
+	StreamingURL = previousStreamingURL
+}
+
+func TestParseStreamEventSpec(t *testing.T) {
+	fields := strings.Split(StreamParams, ",")
+
+	Convey("Should leave empty fields nil", t, func() {
+		event, err := parseStreamEvent(StreamEventString, fields)
+		So(err, ShouldBeNil)
+		So(*event.Speed, ShouldEqual, 65)
+		So(*event.Odometer, ShouldEqual, 9550.3)
+		So(event.Power, ShouldBeNil)
+		So(event.ShiftState, ShouldBeNil)
+		So(event.Errors, ShouldBeEmpty)
+		So(event.Raw, ShouldEqual, StreamEventString)
+	})
+
+	Convey("Should report the fields that can not be parsed", t, func() {
+		line := `1460905367,fast,9550.3,88,10,76,30.493001,north,0,P,227,184,75`
+		event, err := parseStreamEvent(line, fields)
+		So(err, ShouldBeNil)
+		So(event.Speed, ShouldBeNil)
+		So(event.EstLng, ShouldBeNil)
+		So(*event.Power, ShouldEqual, 0)
+		So(*event.ShiftState, ShouldEqual, "P")
+		So(event.Raw, ShouldEqual, line)
+		So(len(event.Errors), ShouldEqual, 2)
+		So(event.Errors[0].Field, ShouldEqual, StreamFieldSpeed)
+		So(event.Errors[0].Value, ShouldEqual, "fast")
+		So(errors.Is(event.Errors[0], strconv.ErrSyntax), ShouldBeTrue)
+		So(event.Errors[1].Field, ShouldEqual, StreamFieldEstLng)
+	})
+}
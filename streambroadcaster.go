@@ -0,0 +1,203 @@
+package tesla
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DropPolicy decides what happens to an event when the buffer of a
+// subscriber is full
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest buffered event to make room
+	DropOldest DropPolicy = iota
+	// DropNewest discards the event that does not fit
+	DropNewest
+	// BlockWhenFull waits for the subscriber, holding up every other
+	// subscriber and the stream itself
+	BlockWhenFull
+)
+
+// StreamBroadcaster fans the events of one stream out to many subscribers,
+// each with its own buffer and drop policy
+type StreamBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[*StreamSubscription]struct{}
+	closed      bool
+}
+
+// StreamSubscription receives the events published by a StreamBroadcaster
+type StreamSubscription struct {
+	broadcaster *StreamBroadcaster
+	policy      DropPolicy
+	events      chan *StreamEvent
+	done        chan struct{}
+	once        sync.Once
+	dropped     int64
+
+	// Guards closed, and the sends in flight that must finish before events
+	// is closed
+	mu      sync.Mutex
+	closed  bool
+	sending sync.WaitGroup
+}
+
+// Generates a new broadcaster without any subscribers
+func NewStreamBroadcaster() *StreamBroadcaster {
+	return &StreamBroadcaster{
+		subscribers: map[*StreamSubscription]struct{}{},
+	}
+}
+
+// Subscribe adds a subscriber buffering up to buffer events, which are
+// dropped according to policy once the buffer is full. The buffer of the
+// drop policies holds at least one event.
+func (b *StreamBroadcaster) Subscribe(buffer int, policy DropPolicy) *StreamSubscription {
+	if policy != BlockWhenFull && buffer < 1 {
+		buffer = 1
+	}
+	subscription := &StreamSubscription{
+		broadcaster: b,
+		policy:      policy,
+		events:      make(chan *StreamEvent, buffer),
+		done:        make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		subscription.close()
+		return subscription
+	}
+	b.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+// Publish sends the event to every subscriber. It only returns early, with
+// the context error, when ctx is done while waiting for a subscriber with the
+// BlockWhenFull policy.
+func (b *StreamBroadcaster) Publish(ctx context.Context, event *StreamEvent) error {
+	b.mu.Lock()
+	subscriptions := make([]*StreamSubscription, 0, len(b.subscribers))
+	for subscription := range b.subscribers {
+		subscriptions = append(subscriptions, subscription)
+	}
+	b.mu.Unlock()
+
+	for _, subscription := range subscriptions {
+		if err := subscription.send(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run publishes the events until the channel is closed or ctx is cancelled,
+// then closes the channels of every subscriber. The events may come from
+// Stream or from a StreamSupervisor.
+func (b *StreamBroadcaster) Run(ctx context.Context, events <-chan *StreamEvent) error {
+	defer b.Close()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := b.Publish(ctx, event); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close removes every subscriber and closes their channels, later
+// subscriptions are closed right away
+func (b *StreamBroadcaster) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subscriptions := b.subscribers
+	b.subscribers = map[*StreamSubscription]struct{}{}
+	b.mu.Unlock()
+
+	for subscription := range subscriptions {
+		subscription.close()
+	}
+}
+
+// Events returns the channel of events of the subscriber, which is closed
+// once the subscriber unsubscribes or the broadcaster is closed
+func (s *StreamSubscription) Events() <-chan *StreamEvent {
+	return s.events
+}
+
+// Dropped returns the number of events dropped for the subscriber
+func (s *StreamSubscription) Dropped() int {
+	return int(atomic.LoadInt64(&s.dropped))
+}
+
+// Unsubscribe stops the delivery of events and closes the channel of the
+// subscriber
+func (s *StreamSubscription) Unsubscribe() {
+	s.broadcaster.mu.Lock()
+	delete(s.broadcaster.subscribers, s)
+	s.broadcaster.mu.Unlock()
+	s.close()
+}
+
+// Sends the event according to the drop policy of the subscriber
+func (s *StreamSubscription) send(ctx context.Context, event *StreamEvent) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.sending.Add(1)
+	s.mu.Unlock()
+	defer s.sending.Done()
+
+	switch s.policy {
+	case BlockWhenFull:
+		select {
+		case s.events <- event:
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case DropNewest:
+		select {
+		case s.events <- event:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	default:
+		for {
+			select {
+			case s.events <- event:
+				return nil
+			default:
+			}
+			select {
+			case <-s.events:
+				atomic.AddInt64(&s.dropped, 1)
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Closes the channel of the subscriber once the sends in flight, which are
+// woken up through done, have finished
+func (s *StreamSubscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		s.sending.Wait()
+		close(s.events)
+	})
+}
@@ -0,0 +1,120 @@
+package tesla
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Returns the raw lines of the events buffered for the subscriber
+func bufferedEvents(subscription *StreamSubscription) []string {
+	raw := []string{}
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return raw
+			}
+			raw = append(raw, event.Raw)
+		default:
+			return raw
+		}
+	}
+}
+
+func TestStreamBroadcasterSpec(t *testing.T) {
+	events := []*StreamEvent{{Raw: "1"}, {Raw: "2"}, {Raw: "3"}}
+
+	Convey("Should deliver every event to every subscriber with room", t, func() {
+		broadcaster := NewStreamBroadcaster()
+		first := broadcaster.Subscribe(3, DropNewest)
+		second := broadcaster.Subscribe(3, BlockWhenFull)
+		for _, event := range events {
+			So(broadcaster.Publish(context.Background(), event), ShouldBeNil)
+		}
+		So(bufferedEvents(first), ShouldResemble, []string{"1", "2", "3"})
+		So(bufferedEvents(second), ShouldResemble, []string{"1", "2", "3"})
+	})
+
+	Convey("Should drop events according to the policy of each subscriber", t, func() {
+		broadcaster := NewStreamBroadcaster()
+		newest := broadcaster.Subscribe(1, DropNewest)
+		oldest := broadcaster.Subscribe(2, DropOldest)
+		for _, event := range events {
+			So(broadcaster.Publish(context.Background(), event), ShouldBeNil)
+		}
+		So(bufferedEvents(newest), ShouldResemble, []string{"1"})
+		So(newest.Dropped(), ShouldEqual, 2)
+		So(bufferedEvents(oldest), ShouldResemble, []string{"2", "3"})
+		So(oldest.Dropped(), ShouldEqual, 1)
+	})
+
+	Convey("Should block for a full subscriber until the context is done", t, func() {
+		broadcaster := NewStreamBroadcaster()
+		broadcaster.Subscribe(0, BlockWhenFull)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := broadcaster.Publish(ctx, events[0])
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+	})
+
+	Convey("Should report dropped events while a send is blocked", t, func() {
+		broadcaster := NewStreamBroadcaster()
+		subscription := broadcaster.Subscribe(0, BlockWhenFull)
+		ctx, cancel := context.WithCancel(context.Background())
+		published := make(chan error)
+		go func() {
+			published <- broadcaster.Publish(ctx, events[0])
+		}()
+		time.Sleep(10 * time.Millisecond)
+		dropped := make(chan int)
+		go func() {
+			dropped <- subscription.Dropped()
+		}()
+		select {
+		case count := <-dropped:
+			So(count, ShouldEqual, 0)
+		case <-time.After(time.Second):
+			So("Dropped blocked behind the publish", ShouldBeEmpty)
+		}
+		cancel()
+		So(errors.Is(<-published, context.Canceled), ShouldBeTrue)
+	})
+
+	Convey("Should stop blocking once the subscriber unsubscribes", t, func() {
+		broadcaster := NewStreamBroadcaster()
+		subscription := broadcaster.Subscribe(0, BlockWhenFull)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			subscription.Unsubscribe()
+		}()
+		err := broadcaster.Publish(context.Background(), events[0])
+		So(err, ShouldBeNil)
+		_, open := <-subscription.Events()
+		So(open, ShouldBeFalse)
+	})
+
+	Convey("Should close every subscriber once the stream ends", t, func() {
+		broadcaster := NewStreamBroadcaster()
+		subscription := broadcaster.Subscribe(3, DropOldest)
+		source := make(chan *StreamEvent)
+		go func() {
+			for _, event := range events {
+				source <- event
+			}
+			close(source)
+		}()
+		err := broadcaster.Run(context.Background(), source)
+		So(err, ShouldBeNil)
+		So(bufferedEvents(subscription), ShouldResemble, []string{"1", "2", "3"})
+		_, open := <-subscription.Events()
+		So(open, ShouldBeFalse)
+
+		late := broadcaster.Subscribe(1, DropNewest)
+		_, open = <-late.Events()
+		So(open, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,224 @@
+package tesla
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// The connection status of a supervised stream
+type StreamStatus int
+
+const (
+	StreamConnecting StreamStatus = iota
+	StreamConnected
+	StreamDisconnected
+	StreamStopped
+)
+
+// The number of errors and status transitions buffered for slow readers
+const streamSupervisorBuffer = 16
+
+// Returns the name of the status
+func (s StreamStatus) String() string {
+	switch s {
+	case StreamConnecting:
+		return "connecting"
+	case StreamConnected:
+		return "connected"
+	case StreamDisconnected:
+		return "disconnected"
+	case StreamStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// StreamSupervisor keeps a stream of the vehicle open, reconnecting with
+// backoff whenever it ends. The events of every connection are delivered on
+// the same channel for as long as the supervisor runs, and the tokens used
+// by the stream are refreshed when the stream rejects them. Refreshed
+// streaming tokens are kept by the supervisor and read through Vehicle.
+type StreamSupervisor struct {
+	// BaseDelay and MaxDelay bound the exponential backoff between
+	// reconnects
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	opts   []StreamOption
+	events chan *StreamEvent
+	errs   chan error
+	status chan StreamStatus
+
+	// Guards the Tokens of the copy of the vehicle while they are refreshed
+	mu      sync.Mutex
+	vehicle *Vehicle
+}
+
+// Generates a new stream supervisor for a copy of the vehicle, which must
+// have been fetched through a Client. Every connection is requested with the
+// options.
+func NewStreamSupervisor(vehicle *Vehicle, opts ...StreamOption) *StreamSupervisor {
+	supervised := *vehicle
+	return &StreamSupervisor{
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+		vehicle:   &supervised,
+		opts:      opts,
+		events:    make(chan *StreamEvent),
+		errs:      make(chan error, streamSupervisorBuffer),
+		status:    make(chan StreamStatus, streamSupervisorBuffer),
+	}
+}
+
+// Events returns the channel of stream events, which is closed once Run
+// returns
+func (s *StreamSupervisor) Events() <-chan *StreamEvent {
+	return s.events
+}
+
+// Errors returns the channel of errors such as bad messages and failed
+// connection attempts. Errors are dropped when the channel is not read.
+func (s *StreamSupervisor) Errors() <-chan error {
+	return s.errs
+}
+
+// Status returns the channel of connection status transitions. The oldest
+// transitions are dropped when the channel is not read, and StreamStopped is
+// the last status sent before the channel is closed.
+func (s *StreamSupervisor) Status() <-chan StreamStatus {
+	return s.status
+}
+
+// Vehicle returns a copy of the supervised vehicle, including the streaming
+// tokens refreshed so far
+func (s *StreamSupervisor) Vehicle() Vehicle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.vehicle
+}
+
+// Run streams events from the vehicle until ctx is cancelled, then closes
+// the channels of the supervisor and returns the context error. Run must
+// only be called once.
+func (s *StreamSupervisor) Run(ctx context.Context) error {
+	defer s.close()
+	if s.Vehicle().client() == nil {
+		return errNoClient
+	}
+	attempt := 0
+	refreshed := false
+	for {
+		s.setStatus(StreamConnecting)
+		received, err := s.stream(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.setStatus(StreamDisconnected)
+		if received {
+			attempt = 0
+		}
+		if (errors.Is(err, ErrUnauthorized) || errors.Is(err, errNoStreamingToken)) && !refreshed {
+			refreshed = true
+			if err = s.refreshTokens(ctx); err == nil {
+				continue
+			}
+		}
+		refreshed = false
+		if err != nil {
+			s.sendError(err)
+		}
+		attempt++
+		if err = sleepContext(ctx, backoffDelay(s.BaseDelay, s.MaxDelay, attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// Streams events from a single connection until it ends, returning whether
+// any events were received
+func (s *StreamSupervisor) stream(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eventChan, errChan, err := s.Vehicle().StreamContext(ctx, s.opts...)
+	if err != nil {
+		return false, err
+	}
+	s.setStatus(StreamConnected)
+	received := false
+	for {
+		select {
+		case event := <-eventChan:
+			received = true
+			select {
+			case s.events <- event:
+			case <-ctx.Done():
+				return received, ctx.Err()
+			}
+		case err := <-errChan:
+			if err == ErrStreamClosed {
+				return received, nil
+			}
+			if errors.Is(err, ErrStreamClosed) {
+				return received, err
+			}
+			s.sendError(err)
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+	}
+}
+
+// Fetches the vehicles of the client to replace the streaming tokens of the
+// vehicle, or refreshes the OAuth token of the client for websocket streams
+func (s *StreamSupervisor) refreshTokens(ctx context.Context) error {
+	supervised := s.Vehicle()
+	if newStreamConfig(s.opts).websocket {
+		return supervised.client().RefreshTokenContext(ctx)
+	}
+	vehicles, err := supervised.client().VehiclesContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, vehicle := range vehicles {
+		if vehicle.ID == supervised.ID {
+			s.mu.Lock()
+			s.vehicle.Tokens = vehicle.Tokens
+			s.mu.Unlock()
+			return nil
+		}
+	}
+	return ErrVehicleNotFound
+}
+
+// Sends the error unless the channel is full
+func (s *StreamSupervisor) sendError(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+// Sends the status, dropping the oldest status when the channel is full
+func (s *StreamSupervisor) setStatus(status StreamStatus) {
+	for {
+		select {
+		case s.status <- status:
+			return
+		default:
+		}
+		select {
+		case <-s.status:
+		default:
+		}
+	}
+}
+
+// Reports that the supervisor stopped and closes its channels
+func (s *StreamSupervisor) close() {
+	s.setStatus(StreamStopped)
+	close(s.status)
+	close(s.errs)
+	close(s.events)
+}
@@ -0,0 +1,101 @@
+package tesla
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStreamSupervisorSpec(t *testing.T) {
+	var mu sync.Mutex
+	connections := 0
+	tokens := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/1/vehicles":
+			w.Write([]byte(VehiclesJSON))
+		case "/stream/456/":
+			mu.Lock()
+			connections++
+			connection := connections
+			_, token, _ := req.BasicAuth()
+			tokens = append(tokens, token)
+			mu.Unlock()
+			if token != "1" {
+				w.WriteHeader(401)
+				w.Write([]byte("Can't validate token. "))
+				return
+			}
+			w.WriteHeader(200)
+			w.Write([]byte(StreamEventString + "\n"))
+			w.(http.Flusher).Flush()
+			if connection > 2 {
+				<-req.Context().Done()
+			}
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	previousStreamingURL := StreamingURL
+	BaseURL = ts.URL + "/api/1"
+	StreamingURL = ts.URL
+
+	Convey("Should keep streaming on one channel across reconnects", t, func() {
+		vehicle := &Vehicle{ID: 1234, VehicleID: 456, Tokens: []string{"expired"}, c: newTestClient()}
+		supervisor := NewStreamSupervisor(vehicle)
+		supervisor.BaseDelay = time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- supervisor.Run(ctx)
+		}()
+
+		for i := 0; i < 2; i++ {
+			select {
+			case event := <-supervisor.Events():
+				So(*event.Speed, ShouldEqual, 65)
+			case <-time.After(time.Second):
+				So("timed out waiting for an event", ShouldBeEmpty)
+			}
+		}
+		cancel()
+		So(<-done, ShouldEqual, context.Canceled)
+
+		_, open := <-supervisor.Events()
+		So(open, ShouldBeFalse)
+		mu.Lock()
+		So(tokens, ShouldResemble, []string{"expired", "1", "1"})
+		mu.Unlock()
+		So(vehicle.Tokens, ShouldResemble, []string{"expired"})
+		So(supervisor.Vehicle().Tokens, ShouldResemble, []string{"1", "2"})
+
+		statuses := []StreamStatus{}
+		for status := range supervisor.Status() {
+			statuses = append(statuses, status)
+		}
+		So(statuses, ShouldResemble, []StreamStatus{
+			StreamConnecting, StreamDisconnected,
+			StreamConnecting, StreamConnected, StreamDisconnected,
+			StreamConnecting, StreamConnected,
+			StreamStopped,
+		})
+	})
+
+	Convey("Should stop right away without a client", t, func() {
+		previousClient := ActiveClient
+		ActiveClient = nil
+		defer func() { ActiveClient = previousClient }()
+		supervisor := NewStreamSupervisor(&Vehicle{VehicleID: 456, Tokens: []string{"1"}})
+		err := supervisor.Run(context.Background())
+		So(err, ShouldEqual, errNoClient)
+		So(<-supervisor.Status(), ShouldEqual, StreamStopped)
+	})
+
+	BaseURL = previousURL
+	StreamingURL = previousStreamingURL
+}
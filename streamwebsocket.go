@@ -0,0 +1,116 @@
+package tesla
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// A message exchanged with the websocket stream
+type streamMessage struct {
+	MsgType           string `json:"msg_type"`
+	Token             string `json:"token,omitempty"`
+	Value             string `json:"value,omitempty"`
+	Tag               string `json:"tag,omitempty"`
+	ErrorType         string `json:"error_type,omitempty"`
+	ConnectionTimeout int    `json:"connection_timeout,omitempty"`
+}
+
+// Subscribes to the websocket stream of the vehicle with the OAuth token of
+// the client
+func (v Vehicle) streamWebsocket(ctx context.Context, client *Client, config *streamConfig) (chan *StreamEvent, chan error, error) {
+	token, err := client.currentToken(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil {
+		return nil, nil, ErrUnauthorized
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, StreamingWebsocketURL, nil)
+	if err != nil {
+		if resp != nil && resp.Request != nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			return nil, nil, newAPIError(resp.Request, resp, body)
+		}
+		return nil, nil, err
+	}
+	tag := strconv.Itoa(v.VehicleID)
+	err = conn.WriteJSON(&streamMessage{
+		MsgType: "data:subscribe_oauth",
+		Token:   token.AccessToken,
+		Value:   strings.Join(config.fields, ","),
+		Tag:     tag,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	eventChan := make(chan *StreamEvent)
+	errChan := make(chan error)
+	go readWebsocket(ctx, conn, tag, config.fields, eventChan, errChan)
+
+	return eventChan, errChan, nil
+}
+
+// Reads the websocket stream of the vehicle, until the stream reports an
+// error, the connection closes or ctx is cancelled
+func readWebsocket(ctx context.Context, conn *websocket.Conn, tag string, fields []string, eventChan chan *StreamEvent, errChan chan error) {
+	done := make(chan struct{})
+	defer close(done)
+	defer conn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		message := &streamMessage{}
+		if err := conn.ReadJSON(message); err != nil {
+			break
+		}
+		if message.Tag != "" && message.Tag != tag {
+			continue
+		}
+		var err error
+		switch message.MsgType {
+		case "data:update":
+			var streamEvent *StreamEvent
+			streamEvent, err = parseStreamEvent(message.Value, fields)
+			if err == nil {
+				select {
+				case eventChan <- streamEvent:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+		case "data:error":
+			err = &StreamError{Type: message.ErrorType, Message: message.Value}
+		default:
+			continue
+		}
+		select {
+		case errChan <- err:
+		case <-ctx.Done():
+			return
+		}
+		if errors.Is(err, ErrStreamClosed) {
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	select {
+	case errChan <- ErrStreamClosed:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,126 @@
+package tesla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Serves a websocket stream that answers a subscription with the messages,
+// then closes the connection or waits for the client to close it
+func serveWebsocket(t *testing.T, closeAfter bool, messages ...*streamMessage) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		subscribe := &streamMessage{}
+		conn.ReadJSON(subscribe)
+		Convey("Should subscribe with the OAuth token", t, func() {
+			So(subscribe.MsgType, ShouldEqual, "data:subscribe_oauth")
+			So(subscribe.Token, ShouldEqual, "ghi789")
+			So(subscribe.Tag, ShouldEqual, "123")
+			So(subscribe.Value, ShouldEqual, StreamParams)
+		})
+		conn.WriteJSON(&streamMessage{MsgType: "control:hello", ConnectionTimeout: 30000})
+		for _, message := range messages {
+			conn.WriteJSON(message)
+		}
+		if closeAfter {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestStreamWebsocketSpec(t *testing.T) {
+	previousURL := StreamingWebsocketURL
+	vehicle := &Vehicle{VehicleID: 123, c: newTestClient()}
+
+	Convey("Should stream events until the vehicle disconnects", t, func() {
+		ts := serveWebsocket(t, false,
+			&streamMessage{MsgType: "data:update", Tag: "123", Value: StreamEventString},
+			&streamMessage{MsgType: "data:update", Tag: "999", Value: StreamEventString},
+			&streamMessage{MsgType: "data:update", Tag: "123", Value: BadStreamEventString},
+			&streamMessage{MsgType: "data:error", Tag: "123", Value: "disconnected", ErrorType: StreamErrorVehicleDisconnected},
+		)
+		defer ts.Close()
+		StreamingWebsocketURL = "ws" + strings.TrimPrefix(ts.URL, "http")
+
+		eventChan, errChan, err := vehicle.Stream(WithWebsocket())
+		So(err, ShouldBeNil)
+		event := <-eventChan
+		So(*event.Speed, ShouldEqual, 65)
+		err = <-errChan
+		So(err, ShouldEqual, ErrBadStreamMessage)
+		err = <-errChan
+		So(errors.Is(err, ErrStreamClosed), ShouldBeTrue)
+		So(errors.Is(err, ErrVehicleUnavailable), ShouldBeTrue)
+		select {
+		case err = <-errChan:
+			So(err, ShouldBeNil)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	Convey("Should report a rejected token", t, func() {
+		ts := serveWebsocket(t, false,
+			&streamMessage{MsgType: "data:error", Tag: "123", Value: "Can't validate token. ", ErrorType: StreamErrorClientError},
+		)
+		defer ts.Close()
+		StreamingWebsocketURL = "ws" + strings.TrimPrefix(ts.URL, "http")
+
+		_, errChan, err := vehicle.Stream(WithWebsocket())
+		So(err, ShouldBeNil)
+		err = <-errChan
+		So(errors.Is(err, ErrUnauthorized), ShouldBeTrue)
+	})
+
+	Convey("Should report the end of the stream when the connection closes", t, func() {
+		ts := serveWebsocket(t, true)
+		defer ts.Close()
+		StreamingWebsocketURL = "ws" + strings.TrimPrefix(ts.URL, "http")
+
+		_, errChan, err := vehicle.Stream(WithWebsocket())
+		So(err, ShouldBeNil)
+		So(<-errChan, ShouldEqual, ErrStreamClosed)
+	})
+
+	Convey("Should stop streaming once the context is cancelled", t, func() {
+		ts := serveWebsocket(t, false,
+			&streamMessage{MsgType: "data:update", Tag: "123", Value: StreamEventString},
+		)
+		defer ts.Close()
+		StreamingWebsocketURL = "ws" + strings.TrimPrefix(ts.URL, "http")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		eventChan, errChan, err := vehicle.StreamContext(ctx, WithWebsocket())
+		So(err, ShouldBeNil)
+		event := <-eventChan
+		So(*event.Speed, ShouldEqual, 65)
+		cancel()
+		select {
+		case event := <-eventChan:
+			So(event, ShouldBeNil)
+		case err := <-errChan:
+			So(err, ShouldBeNil)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	StreamingWebsocketURL = previousURL
+}
@@ -0,0 +1,166 @@
+package tesla
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFileTokenStoreSpec(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tesla")
+	defer os.RemoveAll(dir)
+	store := NewFileTokenStore(filepath.Join(dir, "token.json"))
+
+	Convey("Should load nothing before a token is saved", t, func() {
+		token, err := store.Load()
+		So(err, ShouldBeNil)
+		So(token, ShouldBeNil)
+	})
+
+	Convey("Should save and load a token", t, func() {
+		err := store.Save(&Token{AccessToken: "ghi789", RefreshToken: "jkl012", Expires: 99999999999})
+		So(err, ShouldBeNil)
+		info, err := os.Stat(store.Path)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+		token, err := store.Load()
+		So(err, ShouldBeNil)
+		So(token.AccessToken, ShouldEqual, "ghi789")
+		So(token.RefreshToken, ShouldEqual, "jkl012")
+		So(token.Expires, ShouldEqual, 99999999999)
+	})
+}
+
+func TestMemoryTokenStoreSpec(t *testing.T) {
+	store := NewMemoryTokenStore()
+
+	Convey("Should save and load a copy of the token", t, func() {
+		token, err := store.Load()
+		So(err, ShouldBeNil)
+		So(token, ShouldBeNil)
+		saved := &Token{AccessToken: "ghi789"}
+		So(store.Save(saved), ShouldBeNil)
+		saved.AccessToken = "changed"
+		token, err = store.Load()
+		So(err, ShouldBeNil)
+		So(token.AccessToken, ShouldEqual, "ghi789")
+	})
+}
+
+// A token store whose saves always fail
+type failingTokenStore struct {
+	MemoryTokenStore
+}
+
+func (s *failingTokenStore) Save(token *Token) error {
+	return errors.New("disk full")
+}
+
+func TestClientTokenStoreSpec(t *testing.T) {
+	ts := serveHTTP(t)
+	defer ts.Close()
+	previousAuthURL := AuthURL
+	previousURL := BaseURL
+	AuthURL = ts.URL + "/oauth/token"
+	BaseURL = ts.URL + "/api/1"
+
+	Convey("Should save the token after logging in", t, func() {
+		store := NewMemoryTokenStore()
+		_, err := NewClient(&Auth{
+			ClientID:     "abc123",
+			ClientSecret: "def456",
+			Email:        "elon@tesla.com",
+			Password:     "go",
+			TokenStore:   store,
+		})
+		So(err, ShouldBeNil)
+		token, _ := store.Load()
+		So(token.AccessToken, ShouldEqual, "ghi789")
+	})
+
+	AuthURL = previousAuthURL
+	BaseURL = previousURL
+
+	logins := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/oauth/token":
+			logins++
+			w.Write([]byte(`{"access_token": "fresh", "expires_in": 3888000}`))
+		case "/api/1/vehicles":
+			w.Write([]byte(VehiclesJSON))
+		}
+	}))
+	defer ts.Close()
+	AuthURL = ts.URL + "/oauth/token"
+	BaseURL = ts.URL + "/api/1"
+
+	Convey("Should use a stored token instead of logging in", t, func() {
+		logins = 0
+		store := NewMemoryTokenStore()
+		store.Save(&Token{AccessToken: "stored", Expires: 99999999999})
+		client, err := NewClient(&Auth{TokenStore: store})
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "stored")
+		So(logins, ShouldEqual, 0)
+	})
+
+	Convey("Should refresh an expired stored token and save the result", t, func() {
+		logins = 0
+		store := NewMemoryTokenStore()
+		store.Save(&Token{AccessToken: "stored", RefreshToken: "jkl012", Expires: 0})
+		client, err := NewClient(&Auth{TokenStore: store})
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+		So(logins, ShouldEqual, 1)
+		token, _ := store.Load()
+		So(token.AccessToken, ShouldEqual, "fresh")
+		So(token.RefreshToken, ShouldEqual, "jkl012")
+	})
+
+	Convey("Should log in and replace a token file that can not be loaded", t, func() {
+		logins = 0
+		dir, _ := ioutil.TempDir("", "tesla")
+		defer os.RemoveAll(dir)
+		store := NewFileTokenStore(filepath.Join(dir, "token.json"))
+		err := ioutil.WriteFile(store.Path, []byte(`{"access_token": "trunc`), 0600)
+		So(err, ShouldBeNil)
+		client, err := NewClient(&Auth{TokenStore: store})
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+		So(logins, ShouldEqual, 1)
+		token, err := store.Load()
+		So(err, ShouldBeNil)
+		So(token.AccessToken, ShouldEqual, "fresh")
+	})
+
+	Convey("Should keep the token when it can not be saved", t, func() {
+		logins = 0
+		saveErrors := []error{}
+		auth := &Auth{
+			TokenStore:  &failingTokenStore{},
+			OnSaveError: func(err error) { saveErrors = append(saveErrors, err) },
+		}
+		client, err := NewClient(auth)
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+		So(len(saveErrors), ShouldEqual, 1)
+
+		client.Token = &Token{AccessToken: "stale", RefreshToken: "jkl012", Expires: 0}
+		_, err = client.Vehicles()
+		So(err, ShouldBeNil)
+		So(client.Token.AccessToken, ShouldEqual, "fresh")
+		So(logins, ShouldEqual, 2)
+		So(len(saveErrors), ShouldEqual, 2)
+		So(saveErrors[1].Error(), ShouldEqual, "disk full")
+	})
+
+	AuthURL = previousAuthURL
+	BaseURL = previousURL
+}
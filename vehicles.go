@@ -1,6 +1,11 @@
 package tesla
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
 
 // Represents the vehicle as returned from the Tesla API
 type Vehicle struct {
@@ -18,6 +23,9 @@ type Vehicle struct {
 	NotificationsEnabled   bool        `json:"notifications_enabled"`
 	BackseatToken          interface{} `json:"backseat_token"`
 	BackseatTokenUpdatedAt interface{} `json:"backseat_token_updated_at"`
+
+	// The client the vehicle was fetched with, used for all of its API calls
+	c *Client
 }
 
 // The response that contains the vehicle details from the Tesla API
@@ -40,8 +48,13 @@ type VehiclesResponse struct {
 
 // Fetches the vehicles associated to a Tesla account via the API
 func (c *Client) Vehicles() (Vehicles, error) {
+	return c.VehiclesContext(context.Background())
+}
+
+// VehiclesContext is like Vehicles but uses ctx for the request
+func (c *Client) VehiclesContext(ctx context.Context) (Vehicles, error) {
 	vehiclesResponse := &VehiclesResponse{}
-	body, err := c.get(BaseURL + "/vehicles")
+	body, err := c.get(ctx, BaseURL+"/vehicles")
 	if err != nil {
 		return nil, err
 	}
@@ -49,5 +62,83 @@ func (c *Client) Vehicles() (Vehicles, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, v := range vehiclesResponse.Response {
+		if v.Vehicle != nil {
+			v.Vehicle.c = c
+		}
+	}
 	return vehiclesResponse.Response, nil
 }
+
+// Vehicle fetches a single vehicle of the account by its ID, returning
+// ErrVehicleNotFound if the response holds no vehicle
+func (c *Client) Vehicle(id int64) (*Vehicle, error) {
+	return c.VehicleContext(context.Background(), id)
+}
+
+// VehicleContext is like Vehicle but uses ctx for the request
+func (c *Client) VehicleContext(ctx context.Context, id int64) (*Vehicle, error) {
+	vehicleResponse := &VehicleResponse{}
+	body, err := c.get(ctx, BaseURL+"/vehicles/"+strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, vehicleResponse)
+	if err != nil {
+		return nil, err
+	}
+	if vehicleResponse.Response == nil {
+		return nil, ErrVehicleNotFound
+	}
+	vehicleResponse.Response.c = c
+	return vehicleResponse.Response, nil
+}
+
+// VehicleByVIN fetches the vehicle of the account with the given VIN,
+// returning ErrVehicleNotFound if there is none
+func (c *Client) VehicleByVIN(vin string) (*Vehicle, error) {
+	return c.VehicleByVINContext(context.Background(), vin)
+}
+
+// VehicleByVINContext is like VehicleByVIN but uses ctx for the request
+func (c *Client) VehicleByVINContext(ctx context.Context, vin string) (*Vehicle, error) {
+	return c.findVehicle(ctx, func(v *Vehicle) bool {
+		return strings.EqualFold(v.Vin, vin)
+	})
+}
+
+// VehicleByName fetches the vehicle of the account with the given display
+// name, returning ErrVehicleNotFound if there is none
+func (c *Client) VehicleByName(displayName string) (*Vehicle, error) {
+	return c.VehicleByNameContext(context.Background(), displayName)
+}
+
+// VehicleByNameContext is like VehicleByName but uses ctx for the request
+func (c *Client) VehicleByNameContext(ctx context.Context, displayName string) (*Vehicle, error) {
+	return c.findVehicle(ctx, func(v *Vehicle) bool {
+		return v.DisplayName == displayName
+	})
+}
+
+// Returns the first vehicle of the account matching the predicate
+func (c *Client) findVehicle(ctx context.Context, match func(*Vehicle) bool) (*Vehicle, error) {
+	vehicles, err := c.VehiclesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vehicles {
+		if v.Vehicle != nil && match(v.Vehicle) {
+			return v.Vehicle, nil
+		}
+	}
+	return nil, ErrVehicleNotFound
+}
+
+// Returns the client bound to the vehicle, falling back to the deprecated
+// ActiveClient for vehicles that were not fetched via a Client
+func (v Vehicle) client() *Client {
+	if v.c != nil {
+		return v.c
+	}
+	return ActiveClient
+}
@@ -1,12 +1,15 @@
 package tesla
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 var (
+	VehicleJSON  = `{"response":{"color":null,"display_name":"Macak","id":1234,"option_codes":"MS04,RENA,AU01","vehicle_id":456,"vin":"abc123","tokens":["1","2"],"state":"online","id_s":"1234","remote_start_enabled":true,"calendar_enabled":true,"notifications_enabled":true,"backseat_token":null,"backseat_token_updated_at":null}}`
 	VehiclesJSON = `{"response":[{"color":null,"display_name":"Macak","id":1234,"option_codes":"MS04,RENA,AU01,BC0R,BP01,BR01,BS00,CDM0,CH00,PBSB,CW02,DA02,DCF0,DRLH,DSH7,DV4W,FG02,HP00,IDPB,IX01,LP01,ME02,MI00,PA00,PF01,PI01,PK00,PS01,PX00,PX4D,QNEB,RFP2,SC01,SP00,SR01,SU01,TM00,TP03,TR01,UTAB,WTSG,WTX0,X001,X003,X007,X011,X013,X019,X024,X027,X028,X031,X037,X040,YF01,COUS","vehicle_id":456,"vin":"abc123","tokens":["1","2"],"state":"online","id_s":"789","remote_start_enabled":true,"calendar_enabled":true,"notifications_enabled":true,"backseat_token":null,"backseat_token_updated_at":null}],"count":1}`
 )
 
@@ -34,6 +37,69 @@ func TestVehiclesSpec(t *testing.T) {
 		So(vehicles[0].CalendarEnabled, ShouldBeTrue)
 	})
 
+	Convey("Should get a vehicle by ID", t, func() {
+		vehicle, err := client.Vehicle(1234)
+		So(err, ShouldBeNil)
+		So(vehicle.DisplayName, ShouldEqual, "Macak")
+		So(vehicle.State, ShouldEqual, "online")
+		_, err = client.Vehicle(5678)
+		So(err, ShouldEqual, ErrVehicleNotFound)
+	})
+
+	Convey("Should get a vehicle by VIN", t, func() {
+		vehicle, err := client.VehicleByVIN("ABC123")
+		So(err, ShouldBeNil)
+		So(vehicle.ID, ShouldEqual, 1234)
+		_, err = client.VehicleByVIN("xyz789")
+		So(err, ShouldEqual, ErrVehicleNotFound)
+	})
+
+	Convey("Should get a vehicle by name", t, func() {
+		vehicle, err := client.VehicleByName("Macak")
+		So(err, ShouldBeNil)
+		So(vehicle.Vin, ShouldEqual, "abc123")
+		_, err = client.VehicleByName("Kocka")
+		So(err, ShouldEqual, ErrVehicleNotFound)
+	})
+
 	AuthURL = previousAuthURL
 	BaseURL = previousURL
 }
+
+func TestVehiclesMultipleClientsSpec(t *testing.T) {
+	var lastAuthorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lastAuthorization = req.Header.Get("Authorization")
+		switch req.URL.String() {
+		case "/api/1/vehicles":
+			w.Write([]byte(VehiclesJSON))
+		case "/api/1/vehicles/1234/command/honk_horn":
+			w.Write([]byte(CommandResponseJSON))
+		}
+	}))
+	defer ts.Close()
+	previousURL := BaseURL
+	BaseURL = ts.URL + "/api/1"
+
+	auth := &Auth{Email: "elon@tesla.com"}
+	first, _ := NewClientWithToken(auth, &Token{AccessToken: "first", Expires: 9999999999})
+	second, _ := NewClientWithToken(auth, &Token{AccessToken: "second", Expires: 9999999999})
+
+	Convey("Should send commands with the token of the client that fetched the vehicle", t, func() {
+		So(ActiveClient, ShouldEqual, second)
+		vehicles, err := first.Vehicles()
+		So(err, ShouldBeNil)
+		err = vehicles[0].HonkHorn()
+		So(err, ShouldBeNil)
+		So(lastAuthorization, ShouldEqual, "Bearer first")
+	})
+
+	Convey("Should fall back to the active client for vehicles built by hand", t, func() {
+		vehicle := &Vehicle{ID: 1234}
+		err := vehicle.HonkHorn()
+		So(err, ShouldBeNil)
+		So(lastAuthorization, ShouldEqual, "Bearer second")
+	})
+
+	BaseURL = previousURL
+}